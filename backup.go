@@ -0,0 +1,44 @@
+package boltdb
+
+import (
+	"io"
+
+	"github.com/boltdb/bolt"
+)
+
+// Backup writes a consistent snapshot of the entire database to w.
+// The snapshot is taken inside a read transaction, so writers are not blocked.
+//
+// Parameters:
+//   - w: The writer receiving the database file contents
+//
+// Returns:
+//   - int64: The number of bytes written
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) Backup(w io.Writer) (int64, error) {
+	var n int64
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// BackupReader returns a reader streaming a consistent snapshot of the database,
+// suitable for copying into an HTTP response or any other io.Writer.
+// The snapshot is produced by a background goroutine writing into a pipe. Errors from
+// the backup transaction are delivered through Read. Closing the reader before it is
+// fully consumed aborts the backup and releases the read transaction.
+//
+// Returns:
+//   - io.ReadCloser: A reader over the database file contents
+//   - error: Always nil; backup errors are reported by Read
+func (b *BoltDatabase) BackupReader() (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := b.Backup(pw)
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
@@ -0,0 +1,47 @@
+package boltdb
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupReaderRestoresDatabase(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "users", "alice", []byte("1"))
+	mustSet(t, db, "users", "bob", []byte("2"))
+	mustSet(t, db, "config", "mode", []byte("fast"))
+
+	r, err := db.BackupReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "restored.db")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewBoltDatabase(path)
+	if err != nil {
+		t.Fatalf("backup is not a valid database: %v", err)
+	}
+	defer restored.Close()
+	equal, diffs, err := EqualContents(db, restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal {
+		t.Fatalf("restored database differs: %v", diffs)
+	}
+}
@@ -1,7 +1,10 @@
 package boltdb
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/boltdb/bolt"
@@ -25,19 +28,52 @@ const (
 type WriteOperation struct {
 	Bucket []byte  // The bucket name as bytes
 	Key    []byte  // The key as bytes
-	Value  *[]byte // The value as bytes (nil for delete operations)
+	Value  []byte  // The value as bytes (nil for delete operations)
 	Op     WriteOp // The operation type (set or delete)
 }
 
+// NewSetOp creates an operation setting key to value in bucket.
+// A nil value is stored as an empty value.
+//
+// Parameters:
+//   - bucket: The bucket to write to
+//   - key: The key to set
+//   - value: The value to store
+//
+// Returns:
+//   - *WriteOperation: The set operation
+func NewSetOp(bucket, key string, value []byte) *WriteOperation {
+	if value == nil {
+		value = []byte{}
+	}
+	return &WriteOperation{Bucket: []byte(bucket), Key: []byte(key), Value: value, Op: OpSet}
+}
+
+// NewDeleteOp creates an operation deleting key from bucket.
+//
+// Parameters:
+//   - bucket: The bucket to delete from
+//   - key: The key to delete
+//
+// Returns:
+//   - *WriteOperation: The delete operation
+func NewDeleteOp(bucket, key string) *WriteOperation {
+	return &WriteOperation{Bucket: []byte(bucket), Key: []byte(key), Op: OpDelete}
+}
+
 // BoltBatch provides a thread-safe way to batch multiple write operations.
 // It groups operations by bucket and can execute them either sequentially or concurrently.
 // This is useful for improving performance when performing many write operations.
 type BoltBatch struct {
 	lck sync.Mutex
 	// bucket -> operations
-	ops map[string][]*WriteOperation
+	ops     map[string][]*WriteOperation
+	opCount int // Total number of queued operations across all buckets
 
-	boltdb *BoltDatabase
+	boltdb      *BoltDatabase
+	autoFlush   bool // Execute and reset instead of failing when Add reaches the limit
+	autoReset   bool // Reset after a successful Execute or ExecuteConcurrentContext
+	concurrency int  // Buckets executed in parallel, below 1 for MAX_CONCURRENT_OPERATIONS
 }
 
 // NewBoltBatch creates a new write batch for the specified database.
@@ -56,19 +92,174 @@ func NewBoltBatch(db *BoltDatabase) *BoltBatch {
 
 // Add adds a write operation to the batch.
 // Operations are grouped by bucket for efficient execution.
+// The batch is full once it holds MAX_SEQUENTIAL_OPERATIONS operations in total,
+// whatever their buckets. When the batch is full, Add fails unless auto-flush is
+// enabled, in which case the queued operations are executed and cleared before the
+// new one is queued.
+// Operations are validated up front, so an invalid one is rejected here rather than
+// failing mid-execution after other buckets have been committed. Operations on reserved
+// buckets are rejected with ErrReservedBucket.
 //
 // Parameters:
 //   - op: The write operation to add to the batch
+//
+// Returns:
+//   - error: An error if the operation is invalid or the batch is full, or any error
+//     from an auto-flush
 func (b *BoltBatch) Add(op *WriteOperation) error {
+	if op == nil {
+		return errors.New("nil operation")
+	}
+	if err := validateWriteOperation(b.boltdb, op); err != nil {
+		return err
+	}
+
 	b.lck.Lock()
 	defer b.lck.Unlock()
-	if len(b.ops) >= MAX_SEQUENTIAL_OPERATIONS {
-		return errors.New("max sequential operations reached")
+	if b.opCount >= MAX_SEQUENTIAL_OPERATIONS {
+		if !b.autoFlush {
+			return errors.New("max sequential operations reached")
+		}
+		if err := b.executeConcurrent(context.Background(), nil, nil); err != nil {
+			return err
+		}
+		b.resetLocked()
 	}
 	b.ops[string(op.Bucket)] = append(b.ops[string(op.Bucket)], op)
+	b.opCount++
 	return nil
 }
 
+// AddSet queues a set of key to value in bucket, building the WriteOperation.
+//
+// Parameters:
+//   - bucket: The bucket to write to
+//   - key: The key to set
+//   - value: The value to store
+//
+// Returns:
+//   - error: Any error from Add
+func (b *BoltBatch) AddSet(bucket, key string, value []byte) error {
+	return b.Add(NewSetOp(bucket, key, value))
+}
+
+// AddDelete queues a delete of key from bucket, building the WriteOperation.
+//
+// Parameters:
+//   - bucket: The bucket to delete from
+//   - key: The key to delete
+//
+// Returns:
+//   - error: Any error from Add
+func (b *BoltBatch) AddDelete(bucket, key string) error {
+	return b.Add(NewDeleteOp(bucket, key))
+}
+
+// SetAutoFlushOnFull controls what Add does when the batch is full.
+// When enabled, Add executes and clears the queued operations and then queues the new
+// one, so producers can keep adding indefinitely. When disabled (the default), Add
+// returns an error once the batch is full.
+//
+// Parameters:
+//   - enabled: Whether to flush automatically when the batch is full
+func (b *BoltBatch) SetAutoFlushOnFull(enabled bool) {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+	b.autoFlush = enabled
+}
+
+// SetResetOnSuccess controls whether the batch clears itself after executing.
+// When enabled, a successful Execute, ExecuteConcurrentContext, ExecuteWithWriteAmp,
+// ExecuteWithResults or ExecuteAtomic resets the batch so it can be reused without applying the same operations twice.
+// A failed execution keeps the operations queued. Disabled by default.
+//
+// Parameters:
+//   - enabled: Whether to reset after a successful execution
+func (b *BoltBatch) SetResetOnSuccess(enabled bool) {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+	b.autoReset = enabled
+}
+
+// Reset discards every queued operation, keeping the batch's settings.
+func (b *BoltBatch) Reset() {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+	b.resetLocked()
+}
+
+// resetLocked discards every queued operation. The caller must hold the batch lock.
+func (b *BoltBatch) resetLocked() {
+	clear(b.ops)
+	b.opCount = 0
+}
+
+// Len returns the total number of queued operations across all buckets.
+//
+// Returns:
+//   - int: The number of pending operations
+func (b *BoltBatch) Len() int {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+	return b.opCount
+}
+
+// SetConcurrency sets how many buckets Execute and ExecuteConcurrentContext write in parallel.
+// Bolt serializes write transactions internally, so a higher limit mostly helps when the
+// batch spans many buckets and time is spent outside the write lock; a lower limit bounds
+// goroutines and memory on constrained targets.
+// New batches use MAX_CONCURRENT_OPERATIONS.
+//
+// Parameters:
+//   - n: The maximum number of buckets executed at once, at least 1
+//
+// Returns:
+//   - error: An error if n is below 1, in which case the limit is unchanged
+func (b *BoltBatch) SetConcurrency(n int) error {
+	if n < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+	b.lck.Lock()
+	defer b.lck.Unlock()
+	b.concurrency = n
+	return nil
+}
+
+// Summary tallies the queued operations by type.
+//
+// Returns:
+//   - int: The number of set operations
+//   - int: The number of delete operations
+//   - int: The number of distinct buckets
+func (b *BoltBatch) Summary() (sets, deletes int, buckets int) {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+
+	for _, ops := range b.ops {
+		for _, op := range ops {
+			switch op.Op {
+			case OpSet:
+				sets++
+			case OpDelete:
+				deletes++
+			}
+		}
+	}
+	return sets, deletes, len(b.ops)
+}
+
+// snapshot returns a copy of the queued operations grouped by bucket.
+func (b *BoltBatch) snapshot() map[string][]*WriteOperation {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+
+	ops := make(map[string][]*WriteOperation, len(b.ops))
+	for bucket, bucketOps := range b.ops {
+		ops[bucket] = append([]*WriteOperation(nil), bucketOps...)
+	}
+	return ops
+}
+
 // SetDB sets the database instance for this batch.
 // This is useful when you need to change the target database after creating the batch.
 //
@@ -78,45 +269,302 @@ func (b *BoltBatch) SetDB(db *BoltDatabase) {
 	b.boltdb = db
 }
 
-// ExecuteConcurrent executes all operations in the batch concurrently.
+// Execute executes all operations in the batch concurrently.
 // Operations are grouped by bucket and executed in separate goroutines.
-// A semaphore limits the number of concurrent operations to 10.
+// A semaphore limits the number of buckets executed at once, 10 by default; see SetConcurrency.
 //
 // Returns:
 //   - error: Any error that occurred during execution
 func (b *BoltBatch) Execute() error {
+	return b.ExecuteConcurrentContext(context.Background())
+}
+
+// ExecuteConcurrentContext executes all operations in the batch concurrently,
+// stopping early when the context is cancelled or a bucket fails.
+// Once the context is done, bucket goroutines that have not yet started their
+// transaction exit without running it. Transactions already in progress complete.
+// The context error is only returned if some buckets were skipped, so a batch that
+// committed every bucket before cancellation reports success.
+//
+// Parameters:
+//   - ctx: The context used to cancel execution
+//
+// Returns:
+//   - error: The first bucket error, or the context error if execution was cancelled
+func (b *BoltBatch) ExecuteConcurrentContext(ctx context.Context) error {
 	b.lck.Lock()
 	defer b.lck.Unlock()
+	if err := b.executeConcurrent(ctx, nil, nil); err != nil {
+		return err
+	}
+	if b.autoReset {
+		b.resetLocked()
+	}
+	return nil
+}
+
+// WriteAmp describes the write work a batch execution caused in the database.
+type WriteAmp struct {
+	TxCommits  int // Number of committed read-write transactions used by the batch
+	PageWrites int // Number of page writes performed
+	PageAllocs int // Number of page allocations
+}
+
+// ExecuteWithWriteAmp executes the batch like Execute and reports the resulting write
+// amplification, measured from the database statistics before and after execution.
+// Writes made concurrently by other callers are included in the page counts, so the
+// numbers are only exact when the batch is the sole writer.
+//
+// Returns:
+//   - WriteAmp: The transactions, page writes and page allocations caused by the batch
+//   - error: Any error that occurred during execution
+func (b *BoltBatch) ExecuteWithWriteAmp() (WriteAmp, error) {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+
+	var mu sync.Mutex
+	committed := make(map[*bolt.Tx]struct{})
+	observe := func(tx *bolt.Tx) {
+		tx.OnCommit(func() {
+			mu.Lock()
+			committed[tx] = struct{}{}
+			mu.Unlock()
+		})
+	}
+
+	before := b.boltdb.db.Load().Stats()
+	err := b.executeConcurrent(context.Background(), observe, nil)
+	after := b.boltdb.db.Load().Stats()
+	if err == nil && b.autoReset {
+		b.resetLocked()
+	}
+	diff := after.Sub(&before)
+
+	return WriteAmp{
+		TxCommits:  len(committed),
+		PageWrites: diff.TxStats.Write,
+		PageAllocs: diff.TxStats.PageCount,
+	}, err
+}
+
+// executeConcurrent runs every bucket's operations, calling observe with each
+// transaction used and done with the outcome of each bucket actually executed,
+// when they are non-nil. The caller must hold the batch lock.
+func (b *BoltBatch) executeConcurrent(ctx context.Context, observe func(tx *bolt.Tx), done func(bucket string, err error)) error {
 	if len(b.ops) == 0 {
 		return nil
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	if len(b.ops) == 1 {
 		for bucket := range b.ops {
-			return b.execOps(bucket, b.ops[bucket])
+			err := b.execOps(bucket, b.ops[bucket], observe)
+			if done != nil {
+				done(bucket, err)
+			}
+			return err
 		}
 		return nil
 	}
 
-	wg := errgroup.Group{}
-	semaphore := make(chan struct{}, min(MAX_CONCURRENT_OPERATIONS, len(b.ops)))
+	// Slots are acquired before each goroutine is launched, so at most cap(semaphore)
+	// goroutines exist at a time and launching stops as soon as the group fails.
+	wg, groupCtx := errgroup.WithContext(ctx)
+	limit := b.concurrency
+	if limit < 1 {
+		limit = MAX_CONCURRENT_OPERATIONS
+	}
+	semaphore := make(chan struct{}, min(limit, len(b.ops)))
 
+	launched := 0
+launch:
 	for bucket, ops := range b.ops {
-		wg.Go(func() error {
-			semaphore <- struct{}{}
+		select {
+		case semaphore <- struct{}{}:
+		case <-groupCtx.Done():
+			break launch
+		}
+		launched++
 
+		wg.Go(func() error {
 			defer func() {
 				<-semaphore
 			}()
-			return b.boltdb.db.Batch(func(tx *bolt.Tx) error {
-				return b.execOpsByBucket(tx, bucket, ops)
-			})
+			if err := groupCtx.Err(); err != nil {
+				return err
+			}
+			err := b.execOps(bucket, ops, observe)
+			if done != nil {
+				done(bucket, err)
+			}
+			return err
 		})
 	}
-	return wg.Wait()
+	if err := wg.Wait(); err != nil {
+		return err
+	}
+	// Launched goroutines that skipped their bucket returned the context error above,
+	// so cancellation only needs reporting here if buckets were never launched.
+	if launched < len(b.ops) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// ExecuteAtomic executes every operation of the batch, across all buckets, in a single
+// read-write transaction, so the whole batch either commits or rolls back. Use it for
+// writes that must stay consistent across buckets, such as moving a record from one
+// bucket to another. Buckets are applied in name order and each bucket's operations in
+// the order they were added. Unlike Execute, nothing runs concurrently.
+// The whole batch is held in one transaction, so it is bounded by what bolt can commit at
+// once: large batches use more memory and hold the write lock longer, which makes this
+// best suited to small batches. The MAX_SEQUENTIAL_OPERATIONS limit on Add keeps batches
+// within the size bolt recommends per transaction.
+//
+// Returns:
+//   - error: Any error that occurred, in which case nothing was written
+func (b *BoltBatch) ExecuteAtomic() error {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+
+	if len(b.ops) == 0 {
+		return nil
+	}
+	buckets := make([]string, 0, len(b.ops))
+	for bucket := range b.ops {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+
+	err := b.boltdb.update(func(tx *bolt.Tx) error {
+		for _, bucket := range buckets {
+			if err := b.execOpsByBucket(tx, bucket, b.ops[bucket]); err != nil {
+				return fmt.Errorf("bucket %s: %w", bucket, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if b.autoReset {
+		b.resetLocked()
+	}
+	return nil
+}
+
+// ErrNotExecuted is reported in a BatchResult for operations whose bucket was never
+// executed because the batch stopped early.
+var ErrNotExecuted = errors.New("operation not executed")
+
+// BatchResult records the outcome of one operation of a batch.
+type BatchResult struct {
+	Bucket    string          // The bucket of the operation
+	Op        *WriteOperation // The operation
+	Succeeded bool            // Whether the operation was committed
+	Err       error           // The error of the operation's bucket, or ErrNotExecuted
+}
+
+// ExecuteWithResults executes the batch like Execute and reports the outcome of every
+// operation. Each bucket's operations commit or fail together, but buckets are committed
+// independently, so a failing batch can be partially applied; the results show exactly
+// which operations were committed and can be retried safely. When one bucket fails, the
+// buckets not yet started are skipped and reported with ErrNotExecuted.
+//
+// Returns:
+//   - []BatchResult: One result per queued operation, sorted by bucket in queued order
+//   - error: The first error that occurred during execution, or nil
+func (b *BoltBatch) ExecuteWithResults() ([]BatchResult, error) {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+
+	var mu sync.Mutex
+	outcomes := make(map[string]error, len(b.ops))
+	err := b.executeConcurrent(context.Background(), nil, func(bucket string, err error) {
+		mu.Lock()
+		outcomes[bucket] = err
+		mu.Unlock()
+	})
+
+	buckets := make([]string, 0, len(b.ops))
+	for bucket := range b.ops {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+
+	results := make([]BatchResult, 0, b.opCount)
+	for _, bucket := range buckets {
+		bucketErr, executed := outcomes[bucket]
+		if !executed {
+			bucketErr = ErrNotExecuted
+		}
+		for _, op := range b.ops[bucket] {
+			results = append(results, BatchResult{
+				Bucket:    bucket,
+				Op:        op,
+				Succeeded: bucketErr == nil,
+				Err:       bucketErr,
+			})
+		}
+	}
+
+	if err == nil && b.autoReset {
+		b.resetLocked()
+	}
+	return results, err
+}
+
+// ExecuteLenient executes every operation in its own transaction, skipping operations
+// that fail instead of aborting the batch.
+// Invalid operations, such as a set without a value, a write to a reserved bucket, or a
+// key or value over bolt's size limits, are collected and returned while the remaining
+// operations are committed.
+// Any other error is treated as a database failure and stops execution.
+//
+// Returns:
+//   - []*WriteOperation: The operations that failed and were skipped
+//   - error: A fatal database error, or nil
+func (b *BoltBatch) ExecuteLenient() (failed []*WriteOperation, err error) {
+	b.lck.Lock()
+	defer b.lck.Unlock()
+
+	for bucket, ops := range b.ops {
+		for _, op := range ops {
+			if validateWriteOperation(b.boltdb, op) != nil {
+				failed = append(failed, op)
+				continue
+			}
+			err := b.boltdb.update(func(tx *bolt.Tx) error {
+				return b.execOpsByBucket(tx, bucket, []*WriteOperation{op})
+			})
+			if err == nil {
+				continue
+			}
+			if !isOperationError(err) {
+				return failed, err
+			}
+			failed = append(failed, op)
+		}
+	}
+	return failed, nil
+}
+
+// isOperationError reports whether err was caused by an invalid operation
+// rather than a failure of the database itself.
+func isOperationError(err error) bool {
+	switch err {
+	case bolt.ErrBucketNameRequired, bolt.ErrKeyRequired, bolt.ErrKeyTooLarge,
+		bolt.ErrValueTooLarge, bolt.ErrIncompatibleValue:
+		return true
+	}
+	return false
 }
 
 // execOpsByBucket executes all operations for a specific bucket within a transaction.
+// Operations go through the same write path as Set and Delete, so indexes, chunking,
+// insertion order and watchers see batch writes too.
 // This is an internal method used by both Execute and ExecuteConcurrent.
 //
 // Parameters:
@@ -127,36 +575,60 @@ func (b *BoltBatch) Execute() error {
 // Returns:
 //   - error: Any error that occurred during execution
 func (b *BoltBatch) execOpsByBucket(tx *bolt.Tx, bucket string, ops []*WriteOperation) error {
-	bucketByte := []byte(bucket)
-	boltBucket, err := tx.CreateBucketIfNotExists(bucketByte)
+	boltBucket, err := b.boltdb.createBucketIfNotExists(tx, bucket)
 	if err != nil {
 		return err
 	}
 	for _, op := range ops {
 		switch op.Op {
 		case OpSet:
-			if op.Value == nil {
-				return errors.New("value is nil")
-			}
-			return boltBucket.Put(op.Key, *op.Value)
+			err = b.boltdb.putKey(tx, boltBucket, bucket, string(op.Key), op.Value)
 		case OpDelete:
-			return boltBucket.Delete(op.Key)
+			err = b.boltdb.deleteKey(tx, boltBucket, bucket, string(op.Key))
+		}
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
 // execOps executes all operations for a specific bucket within a transaction.
-// This is an internal method used by both Execute and ExecuteSequential.
+// It is used by executeConcurrent, which runs each bucket in its own transaction.
 //
 // Parameters:
 //   - bucket: The bucket name
 //   - ops: The operations to execute for this bucket
+//   - observe: An optional function called with the transaction used
 //
 // Returns:
 //   - error: Any error that occurred during execution
-func (b *BoltBatch) execOps(bucket string, ops []*WriteOperation) error {
-	return b.boltdb.db.Batch(func(tx *bolt.Tx) error {
+func (b *BoltBatch) execOps(bucket string, ops []*WriteOperation, observe func(tx *bolt.Tx)) error {
+	return b.boltdb.batch(func(tx *bolt.Tx) error {
+		if observe != nil {
+			observe(tx)
+		}
 		return b.execOpsByBucket(tx, bucket, ops)
 	})
 }
+
+// validateWriteOperation checks that an operation has a bucket outside the reserved
+// namespace of db, a known type, and a value when it is a set.
+func validateWriteOperation(db *BoltDatabase, op *WriteOperation) error {
+	if len(op.Bucket) == 0 {
+		return errors.New("empty bucket")
+	}
+	if err := db.checkBucketName(string(op.Bucket)); err != nil {
+		return err
+	}
+	switch op.Op {
+	case OpSet:
+		if op.Value == nil {
+			return errors.New("set requires value")
+		}
+	case OpDelete:
+	default:
+		return fmt.Errorf("unknown op type %q", op.Op)
+	}
+	return nil
+}
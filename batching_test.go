@@ -0,0 +1,553 @@
+package boltdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestExecuteConcurrentContextSkipsBucketsAfterCancel(t *testing.T) {
+	db := newTestDB(t, nil)
+	batch := db.NewBatch()
+	const buckets = 3 * MAX_CONCURRENT_OPERATIONS
+	for i := 0; i < buckets; i++ {
+		if err := batch.Add(NewSetOp(fmt.Sprintf("b%02d", i), "k", []byte("v"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Hold the write lock so the first buckets block inside their transactions while the
+	// rest wait for a semaphore slot, then cancel before releasing it.
+	tx, err := db.db.Load().Begin(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- batch.ExecuteConcurrentContext(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecuteConcurrentContext = %v, want context.Canceled", err)
+	}
+	if written := len(bucketNames(t, db)); written >= buckets {
+		t.Fatalf("%d of %d buckets written, want some skipped", written, buckets)
+	}
+}
+
+func TestExecuteWithWriteAmpCountsTransactions(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	single := batchOf(t, db, NewSetOp("a", "k1", []byte("v1")), NewSetOp("a", "k2", []byte("v2")))
+	amp, err := single.ExecuteWithWriteAmp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amp.TxCommits != 1 || amp.PageWrites == 0 {
+		t.Fatalf("single bucket: %+v", amp)
+	}
+
+	multi := batchOf(t, db, NewSetOp("a", "k3", []byte("v3")), NewSetOp("b", "k1", []byte("v1")), NewSetOp("c", "k1", []byte("v1")))
+	amp, err = multi.ExecuteWithWriteAmp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Concurrent buckets may be coalesced by bolt's Batch, never split further.
+	if amp.TxCommits < 1 || amp.TxCommits > 3 {
+		t.Fatalf("three buckets: %+v", amp)
+	}
+}
+
+func TestExecuteLenientReportsOnlyFailedOperations(t *testing.T) {
+	db := newTestDB(t, nil)
+	// Both operations pass Add but are rejected by bolt when written.
+	longKey := NewSetOp("a", string(make([]byte, bolt.MaxKeySize+1)), []byte("v"))
+	noKey := NewSetOp("a", "", []byte("v"))
+	batch := batchOf(t, db,
+		NewSetOp("a", "k1", []byte("v1")),
+		longKey,
+		noKey,
+		NewSetOp("b", "k2", []byte("v2")),
+	)
+
+	failed, err := batch.ExecuteLenient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 || !containsOp(failed, longKey) || !containsOp(failed, noKey) {
+		t.Fatalf("failed = %v, want the long-key and empty-key operations", failed)
+	}
+	for bucket, key := range map[string]string{"a": "k1", "b": "k2"} {
+		if v, err := db.Get(bucket, key); err != nil || v == nil {
+			t.Fatalf("%s/%s was not committed: %q, %v", bucket, key, v, err)
+		}
+	}
+}
+
+func containsOp(ops []*WriteOperation, op *WriteOperation) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAutoFlushOnFullPersistsEveryOperation(t *testing.T) {
+	db := newTestDB(t, nil)
+	// Thousands of single-bucket batches; skip the coalescing delay and fsync.
+	db.db.Load().MaxBatchDelay = 0
+	db.db.Load().NoSync = true
+	batch := db.NewBatch()
+	batch.SetAutoFlushOnFull(true)
+
+	total := 2*MAX_SEQUENTIAL_OPERATIONS + 10
+	for i := 0; i < total; i++ {
+		if err := batch.Add(NewSetOp(fmt.Sprintf("b%05d", i), "k", []byte("v"))); err != nil {
+			t.Fatalf("Add %d: %v", i, err)
+		}
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(bucketNames(t, db)); got != total {
+		t.Fatalf("persisted %d operations, want %d", got, total)
+	}
+}
+
+func TestBatchSummaryCountsQueuedOperations(t *testing.T) {
+	db := newTestDB(t, nil)
+	batch := batchOf(t, db,
+		NewSetOp("a", "k1", []byte("v")),
+		NewSetOp("a", "k2", []byte("v")),
+		&WriteOperation{Bucket: []byte("a"), Key: []byte("k3"), Op: OpDelete},
+		NewSetOp("b", "k1", []byte("v")),
+		&WriteOperation{Bucket: []byte("c"), Key: []byte("k1"), Op: OpDelete},
+	)
+
+	sets, deletes, buckets := batch.Summary()
+	if sets != 3 || deletes != 2 || buckets != 3 {
+		t.Fatalf("Summary = %d sets, %d deletes, %d buckets; want 3, 2, 3", sets, deletes, buckets)
+	}
+}
+
+func TestBatchPersistsManyOpsInOneBucket(t *testing.T) {
+	const n = 1000
+	executors := []struct {
+		name string
+		run  func(batch *BoltBatch) error
+	}{
+		{"Execute", (*BoltBatch).Execute},
+		{"ExecuteConcurrentContext", func(batch *BoltBatch) error {
+			return batch.ExecuteConcurrentContext(context.Background())
+		}},
+	}
+
+	for _, ex := range executors {
+		t.Run(ex.name, func(t *testing.T) {
+			db := newTestDB(t, nil)
+			batch := db.NewBatch()
+			for i := range n {
+				if err := batch.Add(NewSetOp("b", fmt.Sprintf("k%04d", i), []byte(strconv.Itoa(i)))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := ex.run(batch); err != nil {
+				t.Fatal(err)
+			}
+
+			all := bucketContents(t, db, "b")
+			if len(all) != n {
+				t.Fatalf("bucket holds %d keys, want %d", len(all), n)
+			}
+			for i := range n {
+				if got := all[fmt.Sprintf("k%04d", i)]; got != strconv.Itoa(i) {
+					t.Fatalf("k%04d = %q, want %d", i, got, i)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchManyMoreBucketsThanConcurrencyLimit(t *testing.T) {
+	const buckets = MAX_CONCURRENT_OPERATIONS * 20
+	const keysPerBucket = 5
+
+	db := newTestDB(t, nil)
+	batch := db.NewBatch()
+	for i := 0; i < buckets; i++ {
+		for j := 0; j < keysPerBucket; j++ {
+			if err := batch.Add(NewSetOp(fmt.Sprintf("b%03d", i), strconv.Itoa(j), []byte("v"))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := batch.ExecuteConcurrentContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	names := bucketNames(t, db)
+	if len(names) != buckets {
+		t.Fatalf("Buckets = %d, want %d", len(names), buckets)
+	}
+	for _, name := range names {
+		if count, err := db.Count(name); err != nil || count != keysPerBucket {
+			t.Fatalf("Count(%s) = %d, %v; want %d", name, count, err, keysPerBucket)
+		}
+	}
+}
+
+func TestAddRejectsInvalidOperations(t *testing.T) {
+	db := newTestDB(t, nil)
+	batch := db.NewBatch()
+	for name, tc := range map[string]struct {
+		op   *WriteOperation
+		want string
+	}{
+		"nil":          {nil, "nil operation"},
+		"empty bucket": {NewSetOp("", "k", []byte("v")), "empty bucket"},
+		"unknown op":   {&WriteOperation{Bucket: []byte("a"), Key: []byte("k"), Op: "rename"}, "unknown op type"},
+		"set no value": {&WriteOperation{Bucket: []byte("a"), Key: []byte("k"), Op: OpSet}, "set requires value"},
+	} {
+		if err := batch.Add(tc.op); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Add error = %v, want %q", name, err, tc.want)
+		}
+	}
+	if sets, deletes, buckets := batch.Summary(); sets+deletes+buckets != 0 {
+		t.Fatalf("rejected operations were queued: %d sets, %d deletes, %d buckets", sets, deletes, buckets)
+	}
+}
+
+func TestBatchOpLimitOnSingleBucket(t *testing.T) {
+	db := newTestDB(t, nil)
+	db.db.Load().NoSync = true
+	batch := db.NewBatch()
+	for i := 0; i < MAX_SEQUENTIAL_OPERATIONS; i++ {
+		if err := batch.Add(NewSetOp("b", strconv.Itoa(i), []byte("v"))); err != nil {
+			t.Fatalf("Add %d: %v", i, err)
+		}
+	}
+	if err := batch.Add(NewSetOp("b", "overflow", []byte("v"))); err == nil {
+		t.Fatal("Add beyond MAX_SEQUENTIAL_OPERATIONS on one bucket succeeded")
+	}
+	if sets, _, _ := batch.Summary(); sets != MAX_SEQUENTIAL_OPERATIONS {
+		t.Fatalf("queued %d sets, want %d", sets, MAX_SEQUENTIAL_OPERATIONS)
+	}
+
+	batch.SetAutoFlushOnFull(true)
+	if err := batch.Add(NewSetOp("b", "overflow", []byte("v"))); err != nil {
+		t.Fatalf("Add with auto-flush: %v", err)
+	}
+	if sets, _, _ := batch.Summary(); sets != 1 {
+		t.Fatalf("queued %d sets after auto-flush, want 1", sets)
+	}
+	if count, err := db.Count("b"); err != nil || count != MAX_SEQUENTIAL_OPERATIONS {
+		t.Fatalf("Count after auto-flush = %d, %v; want %d", count, err, MAX_SEQUENTIAL_OPERATIONS)
+	}
+}
+
+func TestBatchResetAndResetOnSuccess(t *testing.T) {
+	db := newTestDB(t, nil)
+	batch := batchOf(t, db, NewSetOp("a", "k1", []byte("v")), NewSetOp("b", "k2", []byte("v")))
+	if n := batch.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+	batch.Reset()
+	if n := batch.Len(); n != 0 {
+		t.Fatalf("Len after Reset = %d, want 0", n)
+	}
+
+	if err := batch.Add(NewSetOp("a", "k1", []byte("v"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if n := batch.Len(); n != 1 {
+		t.Fatalf("Len after Execute without reset = %d, want 1", n)
+	}
+
+	batch.SetResetOnSuccess(true)
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if n := batch.Len(); n != 0 {
+		t.Fatalf("Len after Execute with reset = %d, want 0", n)
+	}
+	if names := bucketNames(t, db); len(names) != 1 || names[0] != "a" {
+		t.Fatalf("buckets = %v, want only a", names)
+	}
+}
+
+func TestExecuteWithResultsMatchesCommittedState(t *testing.T) {
+	db := newTestDB(t, nil)
+	bad := NewSetOp("b", "", []byte("v"))
+	batch := batchOf(t, db,
+		NewSetOp("a", "k1", []byte("v")),
+		NewSetOp("b", "k2", []byte("v")),
+		bad,
+		NewSetOp("c", "k3", []byte("v")),
+	)
+
+	results, err := batch.ExecuteWithResults()
+	if err == nil {
+		t.Fatal("ExecuteWithResults succeeded despite an empty key")
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	for _, r := range results {
+		if r.Bucket == "b" {
+			if r.Succeeded || r.Err == nil || errors.Is(r.Err, ErrNotExecuted) {
+				t.Fatalf("failing bucket result = %+v", r)
+			}
+			continue
+		}
+		if r.Succeeded != (r.Err == nil) {
+			t.Fatalf("inconsistent result %+v", r)
+		}
+		got, err := db.Get(r.Bucket, string(r.Op.Key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if committed := got != nil; committed != r.Succeeded {
+			t.Fatalf("%s/%s committed=%v but result says %+v", r.Bucket, r.Op.Key, committed, r)
+		}
+	}
+	if got, _ := db.Get("b", "k2"); got != nil {
+		t.Fatal("an operation of the failed bucket was committed")
+	}
+}
+
+func TestExecuteAtomicRollsBackEveryBucketOnFailure(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "a", "k", []byte("old"))
+
+	batch := batchOf(t, db,
+		NewSetOp("a", "k", []byte("new")),
+		NewSetOp("b", "", []byte("v")),
+		NewSetOp("c", "k", []byte("v")),
+	)
+	if err := batch.ExecuteAtomic(); err == nil {
+		t.Fatal("ExecuteAtomic succeeded despite an empty key")
+	}
+	if got := bucketContents(t, db, "a"); got["k"] != "old" {
+		t.Fatalf("a/k = %q after a failed ExecuteAtomic, want old", got["k"])
+	}
+	if names := bucketNames(t, db); len(names) != 1 {
+		t.Fatalf("buckets = %v after a failed ExecuteAtomic, want only a", names)
+	}
+
+	moved := batchOf(t, db, NewDeleteOp("a", "k"), NewSetOp("c", "k", []byte("old")))
+	if err := moved.ExecuteAtomic(); err != nil {
+		t.Fatal(err)
+	}
+	if a, c := bucketContents(t, db, "a"), bucketContents(t, db, "c"); len(a) != 0 || c["k"] != "old" {
+		t.Fatalf("after move: a = %v, c = %v", a, c)
+	}
+}
+
+func TestAddSetAndAddDelete(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "users", "gone", []byte("x"))
+
+	batch := db.NewBatch()
+	if err := batch.AddSet("users", "alice", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.AddDelete("users", "gone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.AddSet("", "k", []byte("v")); err == nil {
+		t.Fatal("AddSet accepted an empty bucket")
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if got := bucketContents(t, db, "users"); len(got) != 1 || got["alice"] != "1" {
+		t.Fatalf("users = %v, want only alice=1", got)
+	}
+}
+
+func TestExecuteAtomicFailureInLastBucketPersistsNothing(t *testing.T) {
+	db := newTestDB(t, nil)
+	batch := db.NewBatch()
+	for _, bucket := range []string{"a", "b", "c"} {
+		if err := batch.AddSet(bucket, "k", []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Buckets are applied in name order, so "z" is written last.
+	if err := batch.AddSet("z", "", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := batch.ExecuteAtomic(); err == nil {
+		t.Fatal("ExecuteAtomic succeeded despite a failing last bucket")
+	}
+	if names := bucketNames(t, db); len(names) != 0 {
+		t.Fatalf("buckets = %v after a failed ExecuteAtomic, want none", names)
+	}
+	if n := batch.Len(); n != 4 {
+		t.Fatalf("Len = %d after a failed ExecuteAtomic, want the 4 operations kept", n)
+	}
+}
+
+func TestNewSetOpStoresNilAsEmptyValue(t *testing.T) {
+	db := newTestDB(t, nil)
+	op := NewSetOp("a", "k", nil)
+	if op.Value == nil {
+		t.Fatal("NewSetOp left a nil value, which Add rejects")
+	}
+	if err := batchOf(t, db, op).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := db.Exists("a", "k"); err != nil || !ok {
+		t.Fatalf("Exists = %v, %v; want an empty value stored", ok, err)
+	}
+}
+
+func TestBatchRejectsReservedBuckets(t *testing.T) {
+	db := newTestDB(t, nil)
+	batch := db.NewBatch()
+
+	if err := batch.AddSet("__meta__:b", "x", []byte("v")); !errors.Is(err, ErrReservedBucket) {
+		t.Fatalf("AddSet on reserved bucket = %v, want ErrReservedBucket", err)
+	}
+	if err := batch.AddDelete("__chunks__:b", "x"); !errors.Is(err, ErrReservedBucket) {
+		t.Fatalf("AddDelete on reserved bucket = %v, want ErrReservedBucket", err)
+	}
+	if err := batch.Add(NewSetOp("__order__:b", "x", []byte("v"))); !errors.Is(err, ErrReservedBucket) {
+		t.Fatalf("Add on reserved bucket = %v, want ErrReservedBucket", err)
+	}
+	if n := batch.Len(); n != 0 {
+		t.Fatalf("Len = %d after rejected operations, want 0", n)
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := db.HasBucket("__meta__:b"); err != nil || exists {
+		t.Fatalf("HasBucket = %v, %v; reserved bucket was created", exists, err)
+	}
+
+	db.SetReservedPrefix("sys.")
+	if err := batch.AddSet("sys.config", "x", []byte("v")); !errors.Is(err, ErrReservedBucket) {
+		t.Fatalf("AddSet with custom reserved prefix = %v, want ErrReservedBucket", err)
+	}
+}
+
+func TestBatchEnforcesMaxBuckets(t *testing.T) {
+	db := newTestDB(t, &Options{MaxBuckets: 1})
+	mustSet(t, db, "a", "k", []byte("v"))
+
+	batch := db.NewBatch()
+	if err := batch.AddSet("a", "k2", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatalf("Execute on existing bucket: %v", err)
+	}
+
+	if err := batch.AddSet("b", "k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Execute(); !errors.Is(err, ErrTooManyBuckets) {
+		t.Fatalf("Execute creating a second bucket = %v, want ErrTooManyBuckets", err)
+	}
+	if err := batch.ExecuteAtomic(); !errors.Is(err, ErrTooManyBuckets) {
+		t.Fatalf("ExecuteAtomic creating a second bucket = %v, want ErrTooManyBuckets", err)
+	}
+	if exists, err := db.HasBucket("b"); err != nil || exists {
+		t.Fatalf("HasBucket = %v, %v; bucket beyond the limit was created", exists, err)
+	}
+}
+
+// lateCancelCtx is a context that reports cancellation from its second Err call on,
+// after the batch has checked it once before starting, without ever closing Done.
+type lateCancelCtx struct {
+	context.Context
+	calls atomic.Int32
+}
+
+func (c *lateCancelCtx) Err() error {
+	if c.calls.Add(1) > 1 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestExecuteConcurrentContextCancelledAfterCommit(t *testing.T) {
+	db := newTestDB(t, nil)
+	batch := db.NewBatch()
+	for _, bucket := range []string{"a", "b", "c"} {
+		if err := batch.AddSet(bucket, "k", []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := &lateCancelCtx{Context: context.Background()}
+	if err := batch.ExecuteConcurrentContext(ctx); err != nil {
+		t.Fatalf("ExecuteConcurrentContext = %v after every bucket committed, want nil", err)
+	}
+	for _, bucket := range []string{"a", "b", "c"} {
+		if got, err := db.Get(bucket, "k"); err != nil || string(got) != "v" {
+			t.Fatalf("Get %s = %q, %v", bucket, got, err)
+		}
+	}
+}
+
+func TestExecuteConcurrentContextCancelledBeforeStart(t *testing.T) {
+	db := newTestDB(t, nil)
+	batch := db.NewBatch()
+	for _, bucket := range []string{"a", "b"} {
+		if err := batch.AddSet(bucket, "k", []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := batch.ExecuteConcurrentContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecuteConcurrentContext = %v, want context.Canceled", err)
+	}
+	if exists, err := db.HasBucket("a"); err != nil || exists {
+		t.Fatalf("HasBucket = %v, %v; cancelled batch wrote", exists, err)
+	}
+}
+
+func TestSetConcurrencyRejectsNonPositive(t *testing.T) {
+	db := newTestDB(t, nil)
+	batch := db.NewBatch()
+	for _, n := range []int{0, -1} {
+		if err := batch.SetConcurrency(n); err == nil {
+			t.Fatalf("SetConcurrency(%d) succeeded", n)
+		}
+	}
+	if err := batch.SetConcurrency(1); err != nil {
+		t.Fatal(err)
+	}
+	for _, bucket := range []string{"a", "b", "c"} {
+		if err := batch.AddSet(bucket, "k", []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := db.Buckets(); err != nil || len(names) != 3 {
+		t.Fatalf("Buckets = %v, %v; want 3 buckets", names, err)
+	}
+}
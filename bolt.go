@@ -1,32 +1,142 @@
 package boltdb
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/boltdb/bolt"
 )
 
+const (
+	// MAX_STREAM_VALUE_SIZE is the default limit on values stored by SetFromReader.
+	MAX_STREAM_VALUE_SIZE = 64 << 20
+
+	// DEFAULT_RESERVED_PREFIX marks bucket names reserved for internal metadata.
+	DEFAULT_RESERVED_PREFIX = "__"
+
+	// liveChunkSize is the number of entries ForEachLive reads per transaction.
+	liveChunkSize = 256
+)
+
+// ErrReservedBucket is returned when a caller writes to a bucket reserved for internal metadata.
+var ErrReservedBucket = errors.New("bucket name is reserved")
+
+// ErrStopIteration can be returned by an iteration callback to stop early.
+// The iteration methods recognize it and return nil instead of an error.
+var ErrStopIteration = errors.New("stop iteration")
+
+// ErrReadOnly is returned when a write is attempted on a database opened read-only.
+var ErrReadOnly = errors.New("database opened read-only")
+
+// ErrCallbackPanic is returned when a user callback panics during iteration.
+var ErrCallbackPanic = errors.New("callback panicked")
+
+// BucketKey identifies a key within a bucket.
+type BucketKey struct {
+	Bucket string // The bucket name
+	Key    string // The key within the bucket
+}
+
+// KeyValue is a key and its value read from a bucket.
+type KeyValue struct {
+	Key   string // The key
+	Value []byte // The value, copied out of the transaction
+}
+
+// KeyError pairs a key with the error found for it, as reported by Validate.
+type KeyError struct {
+	Key string // The key whose entry failed
+	Err error  // The error for the entry
+}
+
+// Error implements the error interface.
+func (e KeyError) Error() string {
+	return fmt.Sprintf("key %s: %v", e.Key, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e KeyError) Unwrap() error {
+	return e.Err
+}
+
 // BoltDatabase represents a single Bolt database instance with basic CRUD operations.
 // It provides a simple interface for key-value storage operations on Bolt databases.
 type BoltDatabase struct {
-	db     *bolt.DB // The underlying Bolt database instance
-	dbPath string   // File path where the database is stored
+	db       atomic.Pointer[bolt.DB] // The underlying Bolt database instance, swapped on reopen
+	dbPath   string                  // File path where the database is stored
+	opts     Options                 // Options the database was opened with
+	mode     os.FileMode             // File mode the database was opened with
+	boltOpts *bolt.Options           // Bolt options the database was opened with, used to reopen it
+	readOnly bool                    // Whether the database was opened read-only
+	closed   atomic.Bool             // Whether Close was called, which disables reopening
+	swapLck  sync.Mutex              // Serializes Close with the handle swaps of reopening and compaction
+
+	bucketLocks    sync.Map     // Application-level bucket locks used by WithBuckets
+	maxStreamSize  int64        // Limit on values stored by SetFromReader, 0 for the default
+	chunkSize      int          // Values larger than this are chunked by Set, 0 to disable
+	reservedPrefix string       // Prefix of reserved bucket names, empty for the default
+	codecs         sync.Map     // Bucket name -> Codec used by Bucket
+	orderedBuckets sync.Map     // Names of buckets whose insertion order is tracked
+	writeGate      sync.RWMutex // Held for writing by Quiesce, for reading by writes
+
+	indexLck sync.RWMutex                // Protects indexes
+	indexes  map[string][]secondaryIndex // Data bucket name -> indexes maintained by putKey and deleteKey
+
+	watchLck sync.RWMutex          // Protects watchers
+	watchers map[*watcher]struct{} // Subscribers to changes made by putKey and deleteKey
 }
 
 // NewBoltDatabase creates a new Bolt database instance at the specified path.
-// The database file will be created with read/write permissions (0600).
+// The database file will be created with read/write permissions (0600) and
+// bolt's default options.
+//
+// Parameters:
+//   - dbPath: The file path where the database should be created/opened
+//
+// Returns:
+//   - *BoltDatabase: A new database instance
+//   - error: Any error from bolt while opening the database
+func NewBoltDatabase(dbPath string) (*BoltDatabase, error) {
+	return NewBoltDatabaseWithOptions(dbPath, 0600, nil)
+}
+
+// NewBoltDatabaseWithOptions creates a new Bolt database instance at the specified path,
+// passing the file mode and options through to bolt.Open.
+// Set opts.Timeout to fail fast when another process holds the file lock, instead of
+// waiting indefinitely.
 //
 // Parameters:
 //   - dbPath: The file path where the database should be created/opened
+//   - mode: The file permissions used when creating the database file
+//   - opts: The bolt options, or nil for bolt's defaults
 //
 // Returns:
-//   - *BoltDatabase: A new database instance, or nil if opening fails
-func NewBoltDatabase(dbPath string) *BoltDatabase {
-	db, err := bolt.Open(dbPath, 0600, nil)
+//   - *BoltDatabase: A new database instance
+//   - error: Any error from bolt while opening the database
+func NewBoltDatabaseWithOptions(dbPath string, mode os.FileMode, opts *bolt.Options) (*BoltDatabase, error) {
+	db, err := bolt.Open(dbPath, mode, opts)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return &BoltDatabase{db: db, dbPath: dbPath}
+	b := &BoltDatabase{
+		dbPath:   dbPath,
+		mode:     mode,
+		boltOpts: opts,
+		readOnly: opts != nil && opts.ReadOnly,
+	}
+	b.db.Store(db)
+	return b, nil
 }
 
 // NewBatch creates a new write batch for the database.
@@ -40,15 +150,21 @@ func (b *BoltDatabase) NewBatch() *BoltBatch {
 
 // Close closes the database connection and releases all resources.
 // This method should be called when the database is no longer needed.
+// A closed database is never reopened by AutoReopenOnError; later operations fail with
+// bolt.ErrDatabaseNotOpen.
 //
 // Returns:
 //   - error: Any error that occurred during closing, or nil if successful
 func (b *BoltDatabase) Close() error {
-	return b.db.Close()
+	b.swapLck.Lock()
+	defer b.swapLck.Unlock()
+	b.closed.Store(true)
+	return b.db.Load().Close()
 }
 
 // Delete removes a key-value pair from the specified bucket.
 // If the bucket doesn't exist, an error is returned.
+// Reserved bucket names are rejected with ErrReservedBucket.
 //
 // Parameters:
 //   - bucketName: The name of the bucket to delete from
@@ -57,17 +173,23 @@ func (b *BoltDatabase) Close() error {
 // Returns:
 //   - error: An error if the bucket doesn't exist or deletion fails
 func (b *BoltDatabase) Delete(bucketName string, key string) error {
-	return b.db.Batch(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return errors.New("bucket not found")
-		}
-		return bucket.Delete([]byte(key))
+	if err := b.checkBucketName(bucketName); err != nil {
+		return err
+	}
+	return b.withReopen(func() error {
+		return b.batch(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(bucketName))
+			if bucket == nil {
+				return errors.New("bucket not found")
+			}
+			return b.deleteKey(tx, bucket, bucketName, key)
+		})
 	})
 }
 
 // Set stores a key-value pair in the specified bucket.
 // If the bucket doesn't exist, it will be created automatically.
+// Reserved bucket names are rejected with ErrReservedBucket.
 //
 // Parameters:
 //   - bucketName: The name of the bucket to store the data in
@@ -77,15 +199,61 @@ func (b *BoltDatabase) Delete(bucketName string, key string) error {
 // Returns:
 //   - error: An error if the operation fails
 func (b *BoltDatabase) Set(bucketName string, key string, value []byte) error {
-	return b.db.Batch(func(tx *bolt.Tx) error {
-		bucket, err := tx.CreateBucketIfNotExists([]byte(bucketName))
-		if err != nil {
-			return err
-		}
-		return bucket.Put([]byte(key), value)
+	if err := b.checkBucketName(bucketName); err != nil {
+		return err
+	}
+	return b.withReopen(func() error {
+		return b.batch(func(tx *bolt.Tx) error {
+			bucket, err := b.createBucketIfNotExists(tx, bucketName)
+			if err != nil {
+				return err
+			}
+			return b.putKey(tx, bucket, bucketName, key, value)
+		})
 	})
 }
 
+// putKey stores value under key, the write path shared by every method storing a value.
+// It maintains indexes, insertion order and chunking, and reports the change to watchers
+// once tx commits.
+func (b *BoltDatabase) putKey(tx *bolt.Tx, bucket *bolt.Bucket, bucketName, key string, value []byte) error {
+	old, err := b.readValue(tx, bucketName, key, bucket.Get([]byte(key)))
+	if err != nil {
+		return err
+	}
+	if err := b.updateIndexes(tx, bucketName, key, old, value); err != nil {
+		return err
+	}
+	b.notifyOnCommit(tx, bucketName, key, value, false)
+	if err := b.trackInsert(tx, bucketName, key); err != nil {
+		return err
+	}
+	return b.putValue(tx, bucket, bucketName, key, value)
+}
+
+// deleteKey removes key, the delete path shared by every method removing a value, with
+// the same bookkeeping as putKey.
+func (b *BoltDatabase) deleteKey(tx *bolt.Tx, bucket *bolt.Bucket, bucketName, key string) error {
+	stored := bucket.Get([]byte(key))
+	old, err := b.readValue(tx, bucketName, key, stored)
+	if err != nil {
+		return err
+	}
+	if err := b.updateIndexes(tx, bucketName, key, old, nil); err != nil {
+		return err
+	}
+	if stored != nil {
+		b.notifyOnCommit(tx, bucketName, key, nil, true)
+	}
+	if err := b.trackDelete(tx, bucketName, key); err != nil {
+		return err
+	}
+	if err := b.deleteChunks(tx, bucketName, key, stored); err != nil {
+		return err
+	}
+	return bucket.Delete([]byte(key))
+}
+
 // Get retrieves a value from the specified bucket by key.
 // If the bucket doesn't exist or the key is not found, nil is returned.
 //
@@ -98,14 +266,17 @@ func (b *BoltDatabase) Set(bucketName string, key string, value []byte) error {
 //   - error: Any error that occurred during the operation
 func (b *BoltDatabase) Get(bucketName, key string) ([]byte, error) {
 	var result []byte
-	err := b.db.View(func(tx *bolt.Tx) error {
-		bucket := tx.Bucket([]byte(bucketName))
-		if bucket == nil {
-			return nil
-		}
+	err := b.withReopen(func() error {
+		return b.db.Load().View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(bucketName))
+			if bucket == nil {
+				return nil
+			}
 
-		result = bucket.Get([]byte(key))
-		return nil
+			var err error
+			result, err = b.readValue(tx, bucketName, key, bucket.Get([]byte(key)))
+			return err
+		})
 	})
 
 	return result, err
@@ -122,13 +293,17 @@ func (b *BoltDatabase) Get(bucketName, key string) ([]byte, error) {
 //   - error: Any error that occurred during the operation
 func (b *BoltDatabase) List(bucketName string) (map[string][]byte, error) {
 	result := make(map[string][]byte)
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		if bucket == nil {
 			return nil
 		}
 		return bucket.ForEach(func(k, v []byte) error {
-			result[string(k)] = v
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			result[string(k)] = value
 			return nil
 		})
 	})
@@ -141,23 +316,68 @@ func (b *BoltDatabase) List(bucketName string) (map[string][]byte, error) {
 // Buckets returns a list of all bucket names in the database.
 //
 // Returns:
-//   - []string: A list of all bucket names in the database
+//   - []string: A list of all bucket names in the database, empty but non-nil if there are none
 //   - error: Any error that occurred during the operation
-func (b *BoltDatabase) Buckets() []string {
+func (b *BoltDatabase) Buckets() ([]string, error) {
 	result := make([]string, 0)
-	err := b.db.View(func(tx *bolt.Tx) error {
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
 		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
 			result = append(result, string(name))
 			return nil
 		})
 	})
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return result
+	return result, nil
 }
 
 // ForEach iterates over all key-value pairs in the specified bucket.
+// Returning ErrStopIteration from fn stops the iteration without an error.
+// If fn panics, the read transaction is rolled back and the panic is returned
+// as an error wrapping ErrCallbackPanic.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to iterate over
+//   - fn: A function that will be called for each key-value pair
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) ForEach(bucketName string, fn func(key, value []byte) error) (err error) {
+	defer recoverCallback(&err)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			return fn(k, value)
+		})
+	})
+	return stopIteration(err)
+}
+
+// stopIteration converts ErrStopIteration returned by a callback into a nil error.
+func stopIteration(err error) error {
+	if errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+	return err
+}
+
+// ForEachLive iterates over all key-value pairs in the specified bucket using a series
+// of short read transactions instead of a single long one.
+// Keys are read in chunks and the cursor re-seeks after the last seen key for each chunk,
+// so writes committed between chunks are picked up. This trades the consistent snapshot
+// of ForEach for freshness: keys inserted behind the cursor are missed, keys inserted ahead
+// of it are observed, and a key may reflect a newer value than its neighbours.
+// The callback runs outside any transaction and receives copies of the key and value.
+// Returning ErrStopIteration from fn stops the iteration without an error.
+// A panic in fn is returned as an error wrapping ErrCallbackPanic.
 //
 // Parameters:
 //   - bucketName: The name of the bucket to iterate over
@@ -165,14 +385,1442 @@ func (b *BoltDatabase) Buckets() []string {
 //
 // Returns:
 //   - error: Any error that occurred during the operation
-func (b *BoltDatabase) ForEach(bucketName string, fn func(key, value []byte) error) error {
-	return b.db.View(func(tx *bolt.Tx) error {
+func (b *BoltDatabase) ForEachLive(bucketName string, fn func(k, v []byte) error) (err error) {
+	defer recoverCallback(&err)
+	var lastKey []byte
+	for {
+		keys := make([][]byte, 0, liveChunkSize)
+		values := make([][]byte, 0, liveChunkSize)
+		err := b.db.Load().View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(bucketName))
+			if bucket == nil {
+				return nil
+			}
+
+			c := bucket.Cursor()
+			var k, v []byte
+			if lastKey == nil {
+				k, v = c.First()
+			} else {
+				k, v = c.Seek(lastKey)
+				if k != nil && bytes.Equal(k, lastKey) {
+					k, v = c.Next()
+				}
+			}
+			for ; k != nil && len(keys) < liveChunkSize; k, v = c.Next() {
+				value, err := b.entryValue(tx, bucketName, k, v)
+				if err != nil {
+					return err
+				}
+				keys = append(keys, append([]byte(nil), k...))
+				values = append(values, append([]byte(nil), value...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := range keys {
+			if err := fn(keys[i], values[i]); err != nil {
+				return stopIteration(err)
+			}
+		}
+		if len(keys) < liveChunkSize {
+			return nil
+		}
+		lastKey = keys[len(keys)-1]
+	}
+}
+
+// BucketSize returns the approximate size of the specified bucket, computed as the sum
+// of all key and value lengths within a single read transaction.
+// Page headers and free space are not included, so the on-disk footprint is somewhat larger.
+// If the bucket doesn't exist, 0 is returned.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to measure
+//
+// Returns:
+//   - int64: The total number of key and value bytes in the bucket
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) BucketSize(bucketName string) (int64, error) {
+	var size int64
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			size += int64(len(k) + valueSize(v))
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// ForEachValue iterates over all values in the specified bucket, skipping the keys.
+// This avoids building key slices when only the values are needed, such as when
+// summing value sizes. Returning ErrStopIteration from fn stops the iteration without an
+// error. A panic in fn is returned as an error wrapping ErrCallbackPanic.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to iterate over
+//   - fn: A function that will be called for each value
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) ForEachValue(bucketName string, fn func(value []byte) error) (err error) {
+	defer recoverCallback(&err)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			if err := fn(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return stopIteration(err)
+}
+
+// GetOrInit returns the value stored under key, or stores def and returns it if the key
+// is absent. The lookup and the write happen in a single read-write transaction.
+// If the bucket doesn't exist, it will be created automatically.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to read from
+//   - key: The key to retrieve or initialize
+//   - def: The default value to store when the key is absent
+//
+// Returns:
+//   - []byte: The existing value, or def if it was just stored
+//   - bool: True if def was stored
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) GetOrInit(bucketName, key string, def []byte) ([]byte, bool, error) {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return nil, false, err
+	}
+	var result []byte
+	var created bool
+	err := b.update(func(tx *bolt.Tx) error {
+		bucket, err := b.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		if hasKey(bucket, []byte(key)) {
+			v, err := b.readValue(tx, bucketName, key, bucket.Get([]byte(key)))
+			if err != nil {
+				return err
+			}
+			result = append([]byte{}, v...)
+			return nil
+		}
+		created = true
+		result = def
+		return b.putKey(tx, bucket, bucketName, key, def)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result, created, nil
+}
+
+// SetMaxStreamSize sets the largest value SetFromReader will accept.
+// A value of zero or less restores the default of MAX_STREAM_VALUE_SIZE.
+//
+// Parameters:
+//   - n: The maximum value size in bytes
+func (b *BoltDatabase) SetMaxStreamSize(n int64) {
+	b.maxStreamSize = n
+}
+
+// SetFromReader reads a value from r and stores it under key in the specified bucket.
+// Reading stops with an error once the stream exceeds the configured maximum size
+// (see SetMaxStreamSize), protecting memory from unbounded input. Bolt keeps whole values
+// in memory during writes and memory-maps them on reads, so very large values remain
+// impractical even below the limit.
+// If the bucket doesn't exist, it will be created automatically.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to store the data in
+//   - key: The key to store
+//   - r: The reader supplying the value
+//
+// Returns:
+//   - int64: The number of bytes stored
+//   - error: An error if reading fails, the value is too large, or the write fails
+func (b *BoltDatabase) SetFromReader(bucketName, key string, r io.Reader) (int64, error) {
+	limit := b.maxStreamSize
+	if limit <= 0 {
+		limit = MAX_STREAM_VALUE_SIZE
+	}
+
+	value, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(value)) > limit {
+		return 0, fmt.Errorf("value exceeds maximum stream size of %d bytes", limit)
+	}
+
+	if err := b.Set(bucketName, key, value); err != nil {
+		return 0, err
+	}
+	return int64(len(value)), nil
+}
+
+// SetReservedPrefix sets the prefix marking bucket names reserved for internal metadata.
+// User writes to buckets with this prefix fail with ErrReservedBucket, while the package's
+// own metadata features write to them directly. An empty prefix restores the default of
+// DEFAULT_RESERVED_PREFIX.
+//
+// Parameters:
+//   - prefix: The reserved bucket name prefix
+func (b *BoltDatabase) SetReservedPrefix(prefix string) {
+	b.reservedPrefix = prefix
+}
+
+// isReserved reports whether a bucket name is in the reserved namespace.
+func (b *BoltDatabase) isReserved(bucketName string) bool {
+	prefix := b.reservedPrefix
+	if prefix == "" {
+		prefix = DEFAULT_RESERVED_PREFIX
+	}
+	return strings.HasPrefix(bucketName, prefix)
+}
+
+// checkBucketName rejects bucket names in the reserved namespace.
+func (b *BoltDatabase) checkBucketName(bucketName string) error {
+	if b.isReserved(bucketName) {
+		return fmt.Errorf("%w: %s", ErrReservedBucket, bucketName)
+	}
+	return nil
+}
+
+// Find scans every bucket for entries matching a predicate and returns their locations.
+// This is a full scan of the database within a single read transaction, so it is meant
+// for ad-hoc searches rather than hot paths. Reserved metadata buckets and nested
+// buckets are skipped. A panic in pred is returned as an error wrapping ErrCallbackPanic.
+//
+// Parameters:
+//   - pred: A function reporting whether an entry matches
+//
+// Returns:
+//   - []BucketKey: The bucket and key of every matching entry, in bucket and key order
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) Find(pred func(bucket, key, value []byte) bool) (_ []BucketKey, err error) {
+	defer recoverCallback(&err)
+	result := make([]BucketKey, 0)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if b.isReserved(string(name)) {
+				return nil
+			}
+			return bucket.ForEach(func(k, v []byte) error {
+				if v == nil {
+					return nil
+				}
+				value, err := b.entryValue(tx, string(name), k, v)
+				if err != nil {
+					return err
+				}
+				if pred(name, k, value) {
+					result = append(result, BucketKey{Bucket: string(name), Key: string(k)})
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// recoverCallback converts a panic raised by a user callback into an error.
+// It must be deferred directly by the method running the callback. Bolt rolls back
+// the managed transaction while the panic unwinds, so the database stays usable.
+func recoverCallback(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: %v", ErrCallbackPanic, r)
+	}
+}
+
+// KeySizes returns the value length of every key in the specified bucket without
+// copying the values. If the bucket doesn't exist, an empty map is returned.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to inspect
+//
+// Returns:
+//   - map[string]int: A map of keys to the length of their values
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) KeySizes(bucketName string) (map[string]int, error) {
+	result := make(map[string]int)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			result[string(k)] = valueSize(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Checkpoint captures the current contents of the specified bucket and returns a
+// function that restores the bucket to exactly that state.
+// The restore function rewrites the bucket in a single read-write transaction, removing
+// keys and nested buckets added since the checkpoint, putting back changed values and
+// resetting its sequence. Changes go through the same path as Set and Delete, so indexes,
+// chunked values and insertion order stay consistent and watchers are notified. If the
+// bucket didn't exist at checkpoint time, restoring deletes it. The captured data is held
+// in memory.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to checkpoint
+//
+// Returns:
+//   - func() error: A function restoring the bucket to the checkpoint
+//   - error: Any error that occurred while capturing the bucket
+func (b *BoltDatabase) Checkpoint(bucketName string) (func() error, error) {
+	var saved map[string][]byte
+	var sequence uint64
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		if bucket == nil {
 			return nil
 		}
+		saved = make(map[string][]byte)
+		sequence = bucket.Sequence()
 		return bucket.ForEach(func(k, v []byte) error {
-			return fn(k, v)
+			if v == nil && bucket.Bucket(k) != nil {
+				return nil
+			}
+			value, err := b.readValue(tx, bucketName, string(k), v)
+			if err != nil {
+				return err
+			}
+			saved[string(k)] = append([]byte{}, value...)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	restore := func() error {
+		return b.update(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(bucketName))
+			if bucket == nil && saved == nil {
+				return nil
+			}
+			if bucket == nil {
+				var err error
+				if bucket, err = tx.CreateBucket([]byte(bucketName)); err != nil {
+					return err
+				}
+			}
+
+			var stale, nested [][]byte
+			err := bucket.ForEach(func(k, v []byte) error {
+				if v == nil && bucket.Bucket(k) != nil {
+					nested = append(nested, append([]byte(nil), k...))
+				} else if _, ok := saved[string(k)]; !ok {
+					stale = append(stale, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, k := range stale {
+				if err := b.deleteKey(tx, bucket, bucketName, string(k)); err != nil {
+					return err
+				}
+			}
+			for _, k := range nested {
+				if err := bucket.DeleteBucket(k); err != nil {
+					return err
+				}
+			}
+			if saved == nil {
+				return tx.DeleteBucket([]byte(bucketName))
+			}
+
+			keys := slices.Sorted(maps.Keys(saved))
+			for _, k := range keys {
+				current, err := b.readValue(tx, bucketName, k, bucket.Get([]byte(k)))
+				if err != nil {
+					return err
+				}
+				if hasKey(bucket, []byte(k)) && bytes.Equal(current, saved[k]) {
+					continue
+				}
+				if err := b.putKey(tx, bucket, bucketName, k, saved[k]); err != nil {
+					return err
+				}
+			}
+			return bucket.SetSequence(sequence)
 		})
+	}
+	return restore, nil
+}
+
+// ReserveIDs atomically advances the sequence of the specified bucket by count and
+// returns the reserved range. The range is inclusive, starts after the previous
+// sequence value, and never overlaps a range reserved by another caller.
+// If the bucket doesn't exist, it will be created automatically.
+//
+// Parameters:
+//   - bucketName: The name of the bucket whose sequence is advanced
+//   - count: The number of IDs to reserve, at least 1
+//
+// Returns:
+//   - uint64: The first reserved ID
+//   - uint64: The last reserved ID
+//   - error: An error if count is zero or the operation fails
+func (b *BoltDatabase) ReserveIDs(bucketName string, count uint64) (start, end uint64, err error) {
+	if count == 0 {
+		return 0, 0, errors.New("count must be at least 1")
+	}
+	if err := b.checkBucketName(bucketName); err != nil {
+		return 0, 0, err
+	}
+
+	err = b.update(func(tx *bolt.Tx) error {
+		bucket, err := b.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		seq := bucket.Sequence()
+		if seq > math.MaxUint64-count {
+			return errors.New("sequence overflow")
+		}
+		start, end = seq+1, seq+count
+		return bucket.SetSequence(end)
 	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// IsEmpty reports whether the specified bucket has no keys. It stops at the first
+// key instead of counting them all. A missing bucket is reported as empty.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to check
+//
+// Returns:
+//   - bool: True if the bucket is missing or empty
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) IsEmpty(bucketName string) (bool, error) {
+	empty := true
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		k, _ := bucket.Cursor().First()
+		empty = k == nil
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return empty, nil
+}
+
+// Modify atomically replaces the value stored under key with the result of fn.
+// The read, the call to fn and the write happen in a single read-write transaction,
+// so concurrent modifications of the same key never lose updates. fn receives a copy
+// of the current value, or nil if the key is absent. Returning nil deletes the key,
+// and returning an error aborts the transaction without writing.
+// If the bucket doesn't exist, it will be created automatically.
+//
+// Parameters:
+//   - bucketName: The name of the bucket holding the key
+//   - key: The key to modify
+//   - fn: A function computing the new value from the current one
+//
+// Returns:
+//   - error: Any error returned by fn or the transaction
+func (b *BoltDatabase) Modify(bucketName, key string, fn func(current []byte) ([]byte, error)) error {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return err
+	}
+	return b.update(func(tx *bolt.Tx) error {
+		bucket, err := b.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+
+		var current []byte
+		if hasKey(bucket, []byte(key)) {
+			v, err := b.readValue(tx, bucketName, key, bucket.Get([]byte(key)))
+			if err != nil {
+				return err
+			}
+			current = append([]byte{}, v...)
+		}
+		next, err := fn(current)
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			return b.deleteKey(tx, bucket, bucketName, key)
+		}
+		return b.putKey(tx, bucket, bucketName, key, next)
+	})
+}
+
+// RecentN returns up to n entries with the largest keys in the specified bucket,
+// in descending key order. For buckets keyed by monotonic sequence numbers, these
+// are the most recent entries. If the bucket doesn't exist, an empty slice is returned.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to read
+//   - n: The maximum number of entries to return
+//
+// Returns:
+//   - []KeyValue: The entries, newest first
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) RecentN(bucketName string, n int) ([]KeyValue, error) {
+	result := make([]KeyValue, 0)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Last(); k != nil && len(result) < n; k, v = c.Prev() {
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			result = append(result, KeyValue{Key: string(k), Value: append([]byte(nil), value...)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetWithCommitHook stores a key-value pair and runs onCommit only after the write
+// has been committed. If the transaction fails or rolls back, onCommit is not called.
+// This is useful for cache invalidation that must not run for writes that never landed.
+// onCommit runs after the write lock is released, so it may itself write to the database.
+// If the bucket doesn't exist, it will be created automatically.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to store the data in
+//   - key: The key to store
+//   - value: The value to store (as bytes)
+//   - onCommit: A function to run after a successful commit
+//
+// Returns:
+//   - error: An error if the operation fails
+func (b *BoltDatabase) SetWithCommitHook(bucketName, key string, value []byte, onCommit func()) error {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return err
+	}
+	// The hook is registered with the transaction so it only fires on commit, but it is
+	// called once update has released the write gate: a hook that writes would otherwise
+	// deadlock against a Quiesce waiting for the gate.
+	var committed func()
+	err := b.update(func(tx *bolt.Tx) error {
+		bucket, err := b.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		if err := b.putKey(tx, bucket, bucketName, key, value); err != nil {
+			return err
+		}
+		tx.OnCommit(func() { committed = onCommit })
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if committed != nil {
+		committed()
+	}
+	return nil
+}
+
+// Rotate moves the contents of the active bucket into a new archive bucket and leaves
+// the active bucket empty, all in a single read-write transaction.
+// Bolt cannot rename buckets, so the data is copied into the archive and the active
+// bucket is recreated. Both keep the active bucket's sequence, so IDs reserved after the
+// rotation never repeat archived ones. The active bucket's sidecar buckets for chunks,
+// metadata and insertion order move to the archive, the moved keys are removed from the
+// active bucket's indexes, and watchers of the active bucket see them deleted. If the
+// active bucket doesn't exist, an empty archive is created.
+//
+// Parameters:
+//   - activeBucket: The name of the bucket receiving new writes
+//   - archiveBucket: The name of the archive bucket, which must not exist yet
+//
+// Returns:
+//   - error: An error if the archive bucket exists or the operation fails
+func (b *BoltDatabase) Rotate(activeBucket, archiveBucket string) error {
+	if err := b.checkBucketName(activeBucket); err != nil {
+		return err
+	}
+	if err := b.checkBucketName(archiveBucket); err != nil {
+		return err
+	}
+	return b.update(func(tx *bolt.Tx) error {
+		for _, prefix := range append([]string{""}, sidecarPrefixes...) {
+			if tx.Bucket([]byte(prefix+archiveBucket)) != nil {
+				return fmt.Errorf("archive bucket %s already exists", prefix+archiveBucket)
+			}
+		}
+		archive, err := tx.CreateBucket([]byte(archiveBucket))
+		if err != nil {
+			return err
+		}
+
+		var sequence uint64
+		if active := tx.Bucket([]byte(activeBucket)); active != nil {
+			sequence = active.Sequence()
+			err := active.ForEach(func(k, v []byte) error {
+				if v == nil && active.Bucket(k) != nil {
+					return nil
+				}
+				old, err := b.readValue(tx, activeBucket, string(k), v)
+				if err != nil {
+					return err
+				}
+				b.notifyOnCommit(tx, activeBucket, string(k), nil, true)
+				return b.updateIndexes(tx, activeBucket, string(k), old, nil)
+			})
+			if err != nil {
+				return err
+			}
+			if err := copyBucket(archive, active); err != nil {
+				return err
+			}
+			if err := tx.DeleteBucket([]byte(activeBucket)); err != nil {
+				return err
+			}
+			for _, prefix := range sidecarPrefixes {
+				if err := moveBucket(tx, prefix+activeBucket, prefix+archiveBucket); err != nil {
+					return err
+				}
+			}
+		}
+		active, err := tx.CreateBucket([]byte(activeBucket))
+		if err != nil {
+			return err
+		}
+		return active.SetSequence(sequence)
+	})
+}
+
+// errUnchanged is returned from SetIfChanged's write transaction to roll it back when
+// the value turns out to be unchanged.
+var errUnchanged = errors.New("value unchanged")
+
+// SetIfChanged stores a key-value pair only if the value differs from the stored one.
+// The value is first compared in a read-only transaction, so an unchanged value costs
+// no write transaction or disk sync. Otherwise the comparison is repeated inside the
+// read-write transaction, which is rolled back if a concurrent write already stored
+// the same value.
+// If the bucket doesn't exist, it will be created automatically.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to store the data in
+//   - key: The key to store
+//   - value: The value to store (as bytes)
+//
+// Returns:
+//   - bool: True if the value was written
+//   - error: An error if the operation fails
+func (b *BoltDatabase) SetIfChanged(bucketName, key string, value []byte) (bool, error) {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return false, err
+	}
+
+	var unchanged bool
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		var err error
+		unchanged, err = b.storedEquals(tx, bucketName, key, value)
+		return err
+	})
+	if err != nil || unchanged {
+		return false, err
+	}
+
+	err = b.update(func(tx *bolt.Tx) error {
+		unchanged, err := b.storedEquals(tx, bucketName, key, value)
+		if err != nil {
+			return err
+		}
+		if unchanged {
+			return errUnchanged
+		}
+		bucket, err := b.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		return b.putKey(tx, bucket, bucketName, key, value)
+	})
+	if errors.Is(err, errUnchanged) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// storedEquals reports whether key exists in bucketName with the logical value value.
+func (b *BoltDatabase) storedEquals(tx *bolt.Tx, bucketName, key string, value []byte) (bool, error) {
+	bucket := tx.Bucket([]byte(bucketName))
+	if bucket == nil || !hasKey(bucket, []byte(key)) {
+		return false, nil
+	}
+	current, err := b.readValue(tx, bucketName, key, bucket.Get([]byte(key)))
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(current, value), nil
+}
+
+// GroupByPrefix groups the keys of the specified bucket by their first segment,
+// the part before the first occurrence of sep. Keys without sep are grouped under
+// the empty string. Within each group, keys keep their sorted order.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to read
+//   - sep: The separator ending the first segment
+//
+// Returns:
+//   - map[string][]string: A map of first segments to the full keys in that group
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) GroupByPrefix(bucketName string, sep string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			key := string(k)
+			group := ""
+			if segment, _, found := strings.Cut(key, sep); found {
+				group = segment
+			}
+			result[group] = append(result[group], key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DescribeSchema returns up to samplesPerBucket example entries from every bucket,
+// giving a quick overview of a database without dumping it. Samples are the first
+// keys of each bucket in key order. Reserved metadata buckets are skipped.
+//
+// Parameters:
+//   - samplesPerBucket: The maximum number of entries to sample from each bucket
+//
+// Returns:
+//   - map[string][]KeyValue: A map of bucket names to their sample entries
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) DescribeSchema(samplesPerBucket int) (map[string][]KeyValue, error) {
+	result := make(map[string][]KeyValue)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if b.isReserved(string(name)) {
+				return nil
+			}
+			samples := make([]KeyValue, 0, max(samplesPerBucket, 0))
+			c := bucket.Cursor()
+			for k, v := c.First(); k != nil && len(samples) < samplesPerBucket; k, v = c.Next() {
+				value, err := b.entryValue(tx, string(name), k, v)
+				if err != nil {
+					return err
+				}
+				samples = append(samples, KeyValue{Key: string(k), Value: append([]byte(nil), value...)})
+			}
+			result[string(name)] = samples
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CheckReferences scans fromBucket for references into toBucket and returns the keys
+// whose referenced target is missing. Both buckets are read in a single read transaction,
+// so the result reflects one consistent snapshot.
+//
+// Parameters:
+//   - fromBucket: The name of the bucket holding the references
+//   - toBucket: The name of the bucket the references point into
+//   - extractRef: A function returning the referenced key of an entry, if it has one
+//
+// Returns:
+//   - []string: The keys in fromBucket with dangling references, in key order
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) CheckReferences(fromBucket, toBucket string, extractRef func(key string, value []byte) (refKey string, hasRef bool)) ([]string, error) {
+	dangling := make([]string, 0)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		from := tx.Bucket([]byte(fromBucket))
+		if from == nil {
+			return nil
+		}
+		to := tx.Bucket([]byte(toBucket))
+		return from.ForEach(func(k, v []byte) error {
+			if v == nil && from.Bucket(k) != nil {
+				return nil
+			}
+			value, err := b.entryValue(tx, fromBucket, k, v)
+			if err != nil {
+				return err
+			}
+			refKey, hasRef := extractRef(string(k), value)
+			if hasRef && (to == nil || !hasKey(to, []byte(refKey))) {
+				dangling = append(dangling, string(k))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dangling, nil
+}
+
+// RenameBucketPrefix renames every bucket starting with oldPrefix so that it starts with
+// newPrefix instead, keeping the rest of the name. All renames happen in a single
+// read-write transaction, and every target name is checked for collisions before any
+// bucket is touched. Bolt cannot rename buckets, so each bucket's data and sequence are
+// copied, and its reserved sidecar buckets for chunks, metadata and insertion order move
+// with it. Reserved buckets are never renamed themselves.
+//
+// Parameters:
+//   - oldPrefix: The prefix of the buckets to rename, not empty
+//   - newPrefix: The prefix replacing oldPrefix
+//
+// Returns:
+//   - int: The number of buckets renamed
+//   - error: An error if oldPrefix is empty, a target bucket already exists or the
+//     operation fails
+func (b *BoltDatabase) RenameBucketPrefix(oldPrefix, newPrefix string) (int, error) {
+	if oldPrefix == "" {
+		return 0, errors.New("old prefix must not be empty")
+	}
+	if oldPrefix == newPrefix {
+		return 0, nil
+	}
+
+	renamed := 0
+	err := b.update(func(tx *bolt.Tx) error {
+		var sources []string
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if strings.HasPrefix(string(name), oldPrefix) && !b.isReserved(string(name)) {
+				sources = append(sources, string(name))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, source := range sources {
+			target := newPrefix + strings.TrimPrefix(source, oldPrefix)
+			if err := b.checkBucketName(target); err != nil {
+				return err
+			}
+			for _, prefix := range append([]string{""}, sidecarPrefixes...) {
+				if tx.Bucket([]byte(prefix+target)) != nil {
+					return fmt.Errorf("bucket %s already exists", prefix+target)
+				}
+			}
+		}
+
+		for _, source := range sources {
+			target := newPrefix + strings.TrimPrefix(source, oldPrefix)
+			for _, prefix := range append([]string{""}, sidecarPrefixes...) {
+				if err := moveBucket(tx, prefix+source, prefix+target); err != nil {
+					return err
+				}
+			}
+		}
+		renamed = len(sources)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return renamed, nil
+}
+
+// sidecarPrefixes are the prefixes of the reserved buckets kept alongside a data bucket.
+var sidecarPrefixes = []string{chunkBucketPrefix, metaBucketPrefix, orderBucketPrefix}
+
+// moveBucket copies the top-level bucket source to the new bucket target, sequences
+// included, and deletes source. A missing source is ignored.
+func moveBucket(tx *bolt.Tx, source, target string) error {
+	src := tx.Bucket([]byte(source))
+	if src == nil {
+		return nil
+	}
+	dst, err := tx.CreateBucket([]byte(target))
+	if err != nil {
+		return err
+	}
+	if err := copyBucket(dst, src); err != nil {
+		return err
+	}
+	return tx.DeleteBucket([]byte(source))
+}
+
+// BucketsContainingKey returns the names of the buckets holding the given key, which is
+// useful when debugging data sharded across buckets. All buckets are checked within a
+// single read transaction. Reserved metadata buckets are skipped.
+//
+// Parameters:
+//   - key: The key to look for
+//
+// Returns:
+//   - []string: The names of the buckets containing the key, sorted
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) BucketsContainingKey(key string) ([]string, error) {
+	result := make([]string, 0)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if !b.isReserved(string(name)) && hasKey(bucket, []byte(key)) {
+				result = append(result, string(name))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Exists reports whether a key is present in the specified bucket without copying its value.
+// A key stored with an empty value exists, while a nested bucket of the same name does not
+// count as a key. Bolt's Get returns nil both for missing keys and, potentially, for empty
+// values, so presence is decided by seeking a cursor to the key instead.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to check
+//   - key: The key to look for
+//
+// Returns:
+//   - bool: True if the key is present, false if it or the bucket is missing
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) Exists(bucketName, key string) (bool, error) {
+	exists := false
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		exists = hasKey(bucket, []byte(key))
+		return nil
+	})
+	return exists, err
+}
+
+// hasKey reports whether key is stored in bucket as a value rather than a nested bucket,
+// even when the value is empty.
+func hasKey(bucket *bolt.Bucket, key []byte) bool {
+	k, _ := bucket.Cursor().Seek(key)
+	return k != nil && bytes.Equal(k, key) && bucket.Bucket(key) == nil
+}
+
+// Count returns the number of keys stored directly in the specified bucket. It walks the
+// bucket with a cursor; nested buckets and the keys inside them are not counted.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to count
+//
+// Returns:
+//   - int: The number of keys, or 0 if the bucket doesn't exist
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) Count(bucketName string) (int, error) {
+	count := 0
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v != nil {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// DropAll deletes every bucket in the database, including reserved metadata buckets and
+// index buckets, in a single read-write transaction. The database stays open and usable,
+// and registered indexes and codecs remain in effect for new writes.
+// Freed pages are reused by later writes but the file does not shrink; use CompactInPlace
+// to reclaim the space on disk.
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) DropAll() error {
+	return b.update(func(tx *bolt.Tx) error {
+		var names [][]byte
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// BatchGet retrieves many keys from the specified bucket in a single read transaction.
+// Keys that are not present are left out of the result, so a key stored with an empty
+// value can be told apart from a missing one. Values are copied out of the transaction.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to read from
+//   - keys: The keys to retrieve
+//
+// Returns:
+//   - map[string][]byte: The value of every key found
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) BatchGet(bucketName string, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for _, key := range keys {
+			k, v := c.Seek([]byte(key))
+			if k == nil || !bytes.Equal(k, []byte(key)) || bucket.Bucket(k) != nil {
+				continue
+			}
+			value, err := b.readValue(tx, bucketName, key, v)
+			if err != nil {
+				return err
+			}
+			result[key] = append([]byte{}, value...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteBucket removes a bucket and everything in it in a single transaction, releasing its
+// pages for reuse at once rather than deleting keys one by one. The bucket's reserved
+// sidecar buckets for chunks, metadata and insertion order are removed too, and indexes
+// over the bucket are emptied.
+// Reserved bucket names are rejected with ErrReservedBucket.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to delete
+//
+// Returns:
+//   - error: An error wrapping bolt.ErrBucketNotFound if the bucket doesn't exist,
+//     or any other error that occurred during the operation
+func (b *BoltDatabase) DeleteBucket(bucketName string) error {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return err
+	}
+
+	b.indexLck.RLock()
+	indexes := b.indexes[bucketName]
+	b.indexLck.RUnlock()
+
+	return b.update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(bucketName)); err != nil {
+			return fmt.Errorf("delete bucket %s: %w", bucketName, err)
+		}
+		sidecars := make([]string, 0, len(sidecarPrefixes)+len(indexes))
+		for _, prefix := range sidecarPrefixes {
+			sidecars = append(sidecars, prefix+bucketName)
+		}
+		for _, idx := range indexes {
+			sidecars = append(sidecars, idx.indexBucket)
+		}
+		for _, name := range sidecars {
+			if err := tx.DeleteBucket([]byte(name)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CreateBucket explicitly creates a bucket, failing if it already exists, which helps
+// detect double initialization. The MaxBuckets option applies as it does to Set.
+// Reserved bucket names are rejected with ErrReservedBucket.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to create
+//
+// Returns:
+//   - error: An error wrapping bolt.ErrBucketExists if the bucket already exists,
+//     or any other error that occurred during the operation
+func (b *BoltDatabase) CreateBucket(bucketName string) error {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return err
+	}
+	return b.update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(bucketName)) != nil {
+			return fmt.Errorf("create bucket %s: %w", bucketName, bolt.ErrBucketExists)
+		}
+		_, err := b.createBucketIfNotExists(tx, bucketName)
+		return err
+	})
+}
+
+// HasBucket reports whether a bucket exists, without listing every bucket name.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to check
+//
+// Returns:
+//   - bool: True if the bucket exists
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) HasBucket(bucketName string) (bool, error) {
+	exists := false
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket([]byte(bucketName)) != nil
+		return nil
+	})
+	return exists, err
+}
+
+// CollisionCheck reports which of the given keys already exist in the specified bucket,
+// so a bulk import can choose between overwriting and skipping them. All keys are checked
+// within a single read transaction.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to check
+//   - keys: The keys about to be imported
+//
+// Returns:
+//   - []string: The keys already present, in the order given and without duplicates
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) CollisionCheck(bucketName string, keys []string) ([]string, error) {
+	result := make([]string, 0)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		seen := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			if !seen[key] && hasKey(bucket, []byte(key)) {
+				result = append(result, key)
+			}
+			seen[key] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Update runs fn in a read-write transaction with the specified bucket, so several reads
+// and writes can be composed into one atomic step. The bucket is created if it doesn't
+// exist, subject to the MaxBuckets option. Returning an error from fn rolls the whole
+// transaction back. Writes made through the bucket bypass indexes, chunking, insertion
+// order and watchers.
+// Reserved bucket names are rejected with ErrReservedBucket.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to update
+//   - fn: A function called with the transaction and the bucket
+//
+// Returns:
+//   - error: Any error returned by fn or the transaction
+func (b *BoltDatabase) Update(bucketName string, fn func(tx *bolt.Tx, bucket *bolt.Bucket) error) error {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return err
+	}
+	return b.update(func(tx *bolt.Tx) error {
+		bucket, err := b.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		return fn(tx, bucket)
+	})
+}
+
+// View runs fn in a read-only transaction with the specified bucket.
+// If the bucket doesn't exist, fn is not called and nil is returned.
+// Slices obtained from the bucket are only valid until fn returns.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to read
+//   - fn: A function called with the bucket
+//
+// Returns:
+//   - error: Any error returned by fn or the transaction
+func (b *BoltDatabase) View(bucketName string, fn func(bucket *bolt.Bucket) error) error {
+	return b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		return fn(bucket)
+	})
+}
+
+// ValueSizeStats gathers value size statistics for the specified bucket in a single read
+// transaction, without copying values out of it. Chunked values count with their full
+// size. Nested buckets are skipped. The average size is total divided by count.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to measure
+//
+// Returns:
+//   - int: The number of values
+//   - int64: The total size of all values in bytes
+//   - int: The size of the largest value in bytes
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) ValueSizeStats(bucketName string) (count int, total int64, max int, err error) {
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if v == nil && bucket.Bucket(k) != nil {
+				return nil
+			}
+			size := valueSize(v)
+			count++
+			total += int64(size)
+			if size > max {
+				max = size
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return count, total, max, nil
+}
+
+// DeleteWhere deletes every entry of the specified bucket for which pred returns true,
+// in a single read-write transaction. Matching entries are collected in a cursor pass and
+// copied, then deleted afterwards, so deletion never disturbs the iteration. Deletions keep
+// indexes, chunked values and insertion order consistent and are reported to watchers
+// like Delete.
+// A panic in pred rolls the transaction back and is returned as an error wrapping
+// ErrCallbackPanic. Reserved bucket names are rejected with ErrReservedBucket.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to delete from
+//   - pred: A function reporting whether an entry should be deleted
+//
+// Returns:
+//   - int: The number of entries deleted
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) DeleteWhere(bucketName string, pred func(key, value []byte) bool) (_ int, err error) {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return 0, err
+	}
+	defer recoverCallback(&err)
+
+	deleted := 0
+	err = b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+
+		var matches []string
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil && bucket.Bucket(k) != nil {
+				continue
+			}
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			if pred(k, value) {
+				matches = append(matches, string(k))
+			}
+		}
+
+		for _, key := range matches {
+			if err := b.deleteKey(tx, bucket, bucketName, key); err != nil {
+				return err
+			}
+		}
+		deleted = len(matches)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// ScratchBucket creates a uniquely named bucket for intermediate results and returns a
+// wrapper for it along with a cleanup function that deletes it. The bucket is an ordinary
+// bucket named "scratch-" followed by random hex, so it survives a crash before cleanup
+// and counts towards the MaxBuckets option while it exists.
+//
+// Returns:
+//   - *BoltDBWrapper: A wrapper for the new bucket
+//   - func() error: Deletes the bucket; once it succeeds, later calls return nil
+//   - error: Any error that occurred while creating the bucket
+func (b *BoltDatabase) ScratchBucket() (w *BoltDBWrapper, cleanup func() error, err error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, nil, err
+	}
+	bucketName := "scratch-" + hex.EncodeToString(suffix)
+	if err := b.CreateBucket(bucketName); err != nil {
+		return nil, nil, err
+	}
+
+	var mu sync.Mutex
+	done := false
+	cleanup = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if done {
+			return nil
+		}
+		if err := b.DeleteBucket(bucketName); err != nil {
+			return err
+		}
+		done = true
+		return nil
+	}
+	return NewBoltDBWrapper(b, bucketName), cleanup, nil
+}
+
+// Validate runs validate over every entry of the specified bucket in a single read
+// transaction and reports the entries it rejects, for data quality checks. Nested buckets
+// are skipped. A panic in validate is returned as an error wrapping ErrCallbackPanic.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to check
+//   - validate: A function returning an error for an invalid entry
+//
+// Returns:
+//   - []KeyError: The rejected keys with their errors, in key order
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) Validate(bucketName string, validate func(key, value []byte) error) (_ []KeyError, err error) {
+	defer recoverCallback(&err)
+	result := make([]KeyError, 0)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if v == nil && bucket.Bucket(k) != nil {
+				return nil
+			}
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			if err := validate(k, value); err != nil {
+				result = append(result, KeyError{Key: string(k), Err: err})
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DistinctValuePrefixes counts how many values of the specified bucket start with each
+// distinct prefix of prefixLen bytes, in a single read transaction, for cardinality
+// estimates. Prefixes are hex-encoded; values shorter than prefixLen count under their
+// whole value. Nested buckets are skipped.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to inspect
+//   - prefixLen: The prefix length in bytes, at least 1
+//
+// Returns:
+//   - map[string]int: The number of values per hex-encoded prefix
+//   - error: An error if prefixLen is below 1 or the operation fails
+func (b *BoltDatabase) DistinctValuePrefixes(bucketName string, prefixLen int) (map[string]int, error) {
+	if prefixLen < 1 {
+		return nil, errors.New("prefix length must be at least 1")
+	}
+	result := make(map[string]int)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if v == nil && bucket.Bucket(k) != nil {
+				return nil
+			}
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			result[hex.EncodeToString(value[:min(prefixLen, len(value))])]++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
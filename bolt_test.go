@@ -0,0 +1,1113 @@
+package boltdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// newTestDB opens a database with opts in a temporary directory that is closed when the test ends.
+func newTestDB(t *testing.T, opts *Options) *BoltDatabase {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// mustSet stores a value, failing the test on error.
+func mustSet(t *testing.T, db *BoltDatabase, bucketName, key string, value []byte) {
+	t.Helper()
+	if err := db.Set(bucketName, key, value); err != nil {
+		t.Fatalf("set %s/%s: %v", bucketName, key, err)
+	}
+}
+
+// bucketNames returns the names of every bucket in db, failing the test on error.
+func bucketNames(t *testing.T, db *BoltDatabase) []string {
+	t.Helper()
+	names, err := db.Buckets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+// bucketContents returns a copy of every key/value pair in the bucket.
+func bucketContents(t *testing.T, db *BoltDatabase, bucketName string) map[string]string {
+	t.Helper()
+	result := make(map[string]string)
+	err := db.ForEach(bucketName, func(k, v []byte) error {
+		result[string(k)] = string(v)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestForEachLiveObservesConcurrentWrites(t *testing.T) {
+	db := newTestDB(t, nil)
+	err := db.db.Load().Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("b"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < liveChunkSize+10; i++ {
+			if err := bucket.Put([]byte(fmt.Sprintf("k%04d", i)), []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	err = db.ForEachLive("b", func(k, v []byte) error {
+		if len(seen) == 0 {
+			// Written while the first chunk is being visited, ahead of the cursor.
+			mustSet(t, db, "b", "z-late", []byte("v"))
+		}
+		seen[string(k)] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !seen["z-late"] {
+		t.Fatal("key written during iteration was not observed")
+	}
+	if len(seen) != liveChunkSize+11 {
+		t.Fatalf("visited %d keys, want %d", len(seen), liveChunkSize+11)
+	}
+}
+
+func TestBucketSizeGrowsWithData(t *testing.T) {
+	db := newTestDB(t, nil)
+	if size, err := db.BucketSize("b"); err != nil || size != 0 {
+		t.Fatalf("BucketSize of missing bucket = %d, %v; want 0", size, err)
+	}
+
+	mustSet(t, db, "b", "k1", make([]byte, 100))
+	first, err := db.BucketSize("b")
+	if err != nil || first != 2+100 {
+		t.Fatalf("BucketSize = %d, %v; want %d", first, err, 2+100)
+	}
+
+	for _, key := range []string{"k2", "k3", "k4"} {
+		mustSet(t, db, "b", key, make([]byte, 100))
+	}
+	size, err := db.BucketSize("b")
+	if err != nil || size != 4*first {
+		t.Fatalf("BucketSize after four entries = %d, %v; want %d", size, err, 4*first)
+	}
+}
+
+func TestForEachValueMatchesForEach(t *testing.T) {
+	db := newTestDB(t, nil)
+	for i := 0; i < 20; i++ {
+		mustSet(t, db, "b", fmt.Sprintf("k%02d", i), make([]byte, i*3))
+	}
+
+	var viaValues, viaForEach int
+	if err := db.ForEachValue("b", func(v []byte) error {
+		viaValues += len(v)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ForEach("b", func(k, v []byte) error {
+		viaForEach += len(v)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if viaValues != viaForEach || viaValues != 3*190 {
+		t.Fatalf("ForEachValue total = %d, ForEach total = %d; want %d", viaValues, viaForEach, 3*190)
+	}
+}
+
+func TestGetOrInit(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	value, created, err := db.GetOrInit("config", "mode", []byte("default"))
+	if err != nil || !created || string(value) != "default" {
+		t.Fatalf("first GetOrInit = %q, %v, %v; want default, true", value, created, err)
+	}
+	value, created, err = db.GetOrInit("config", "mode", []byte("other"))
+	if err != nil || created || string(value) != "default" {
+		t.Fatalf("second GetOrInit = %q, %v, %v; want default, false", value, created, err)
+	}
+	if got, err := db.Get("config", "mode"); err != nil || string(got) != "default" {
+		t.Fatalf("Get = %q, %v; want default", got, err)
+	}
+}
+
+func TestSetFromReader(t *testing.T) {
+	db := newTestDB(t, nil)
+	value := strings.Repeat("stream", 1000)
+
+	n, err := db.SetFromReader("b", "k", strings.NewReader(value))
+	if err != nil || n != int64(len(value)) {
+		t.Fatalf("SetFromReader = %d, %v; want %d", n, err, len(value))
+	}
+	if got, err := db.Get("b", "k"); err != nil || string(got) != value {
+		t.Fatalf("Get = %d bytes, %v; want the streamed value", len(got), err)
+	}
+
+	db.SetMaxStreamSize(10)
+	if _, err := db.SetFromReader("b", "big", strings.NewReader("01234567890")); err == nil {
+		t.Fatal("SetFromReader accepted a value above the limit")
+	}
+	if got, _ := db.Get("b", "big"); got != nil {
+		t.Fatalf("oversized value was stored: %q", got)
+	}
+	if _, err := db.SetFromReader("b", "fits", strings.NewReader("0123456789")); err != nil {
+		t.Fatalf("SetFromReader at the limit: %v", err)
+	}
+}
+
+func TestReservedBucketsRejectUserWrites(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	if err := db.Set("__meta__", "k", []byte("v")); !errors.Is(err, ErrReservedBucket) {
+		t.Fatalf("Set on reserved bucket = %v, want ErrReservedBucket", err)
+	}
+	if err := db.Delete("__meta__", "k"); !errors.Is(err, ErrReservedBucket) {
+		t.Fatalf("Delete on reserved bucket = %v, want ErrReservedBucket", err)
+	}
+	if _, _, err := db.GetOrInit("__meta__", "k", []byte("v")); !errors.Is(err, ErrReservedBucket) {
+		t.Fatalf("GetOrInit on reserved bucket = %v, want ErrReservedBucket", err)
+	}
+
+	// Internal features keep writing to their reserved buckets.
+	dict, err := NewKeyDictWrapper(db, "data", ":")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dict.Set("prefix:key", []byte("v")); err != nil {
+		t.Fatalf("internal write to %s: %v", keyDictBucket, err)
+	}
+	if got, err := dict.Get("prefix:key"); err != nil || string(got) != "v" {
+		t.Fatalf("Get through the key dictionary = %q, %v", got, err)
+	}
+
+	db.SetReservedPrefix("sys.")
+	if err := db.Set("sys.config", "k", []byte("v")); !errors.Is(err, ErrReservedBucket) {
+		t.Fatalf("Set with custom reserved prefix = %v, want ErrReservedBucket", err)
+	}
+	mustSet(t, db, "__now_allowed", "k", []byte("v"))
+}
+
+func TestFindMatchesAcrossBuckets(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "users", "alice", []byte("admin,dev"))
+	mustSet(t, db, "users", "bob", []byte("dev"))
+	mustSet(t, db, "users", "carol", []byte("ops"))
+	mustSet(t, db, "groups", "g1", []byte("admins"))
+
+	found, err := db.Find(func(bucket, key, value []byte) bool {
+		return strings.Contains(string(value), "admin")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, bk := range found {
+		got = append(got, bk.Bucket+"/"+bk.Key)
+	}
+	want := "groups/g1 users/alice"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("Find = %q, want %q", got, want)
+	}
+}
+
+func TestForEachRecoversCallbackPanic(t *testing.T) {
+	db := newTestDB(t, nil)
+	for _, k := range []string{"a", "b", "c"} {
+		mustSet(t, db, "data", k, []byte("v"))
+	}
+
+	err := db.ForEach("data", func(k, v []byte) error {
+		if string(k) == "b" {
+			panic("boom")
+		}
+		return nil
+	})
+	if !errors.Is(err, ErrCallbackPanic) {
+		t.Fatalf("ForEach returned %v, want ErrCallbackPanic", err)
+	}
+
+	// The read transaction was released, so writes and reads still work.
+	mustSet(t, db, "data", "d", []byte("v"))
+	n := 0
+	if err := db.ForEach("data", func(k, v []byte) error { n++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Fatalf("got %d keys after panic, want 4", n)
+	}
+}
+
+func TestKeySizesMatchStoredValues(t *testing.T) {
+	db := newTestDB(t, nil)
+	want := map[string]int{"empty": 0, "short": 3, "long": 4096}
+	for k, n := range want {
+		mustSet(t, db, "data", k, make([]byte, n))
+	}
+
+	sizes, err := db.KeySizes("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sizes) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", sizes, want)
+	}
+
+	if sizes, err := db.KeySizes("missing"); err != nil || len(sizes) != 0 {
+		t.Fatalf("missing bucket: %v, %v", sizes, err)
+	}
+}
+
+func TestCheckpointRestoresBucketExactly(t *testing.T) {
+	db := newTestDB(t, nil)
+	for i := 0; i < 20; i++ {
+		mustSet(t, db, "data", fmt.Sprintf("k%02d", i), []byte(fmt.Sprint(i)))
+	}
+	want := bucketContents(t, db, "data")
+
+	restore, err := db.Checkpoint("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i += 2 {
+		if err := db.Delete("data", fmt.Sprintf("k%02d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 30; i++ {
+		mustSet(t, db, "data", fmt.Sprintf("k%02d", i), []byte("changed"))
+	}
+
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if got := bucketContents(t, db, "data"); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("restored %d keys %v, want %v", len(got), got, want)
+	}
+}
+
+func TestCheckpointOfMissingBucketDeletesIt(t *testing.T) {
+	db := newTestDB(t, nil)
+	restore, err := db.Checkpoint("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, db, "data", "k", []byte("v"))
+
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if buckets := bucketNames(t, db); len(buckets) != 0 {
+		t.Fatalf("buckets after restore: %v", buckets)
+	}
+}
+
+func TestReserveIDsConcurrentRangesAreDisjoint(t *testing.T) {
+	db := newTestDB(t, nil)
+	const workers, perWorker, count = 8, 5, 10
+
+	type idRange struct{ start, end uint64 }
+	ranges := make(chan idRange, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				start, end, err := db.ReserveIDs("ids", count)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ranges <- idRange{start, end}
+			}
+		}()
+	}
+	wg.Wait()
+	close(ranges)
+
+	var all []idRange
+	for r := range ranges {
+		if r.end-r.start+1 != count {
+			t.Fatalf("range %d-%d does not hold %d IDs", r.start, r.end, count)
+		}
+		all = append(all, r)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].start < all[j].start })
+	next := uint64(1)
+	for _, r := range all {
+		if r.start != next {
+			t.Fatalf("range %d-%d, want it to start at %d", r.start, r.end, next)
+		}
+		next = r.end + 1
+	}
+	if next != workers*perWorker*count+1 {
+		t.Fatalf("reserved up to %d, want %d", next-1, workers*perWorker*count)
+	}
+
+	if _, _, err := db.ReserveIDs("ids", 0); err == nil {
+		t.Fatal("expected an error for a zero count")
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	db := newTestDB(t, nil)
+	if empty, err := db.IsEmpty("data"); err != nil || !empty {
+		t.Fatalf("missing bucket: %v, %v", empty, err)
+	}
+
+	mustSet(t, db, "data", "k", []byte("v"))
+	if empty, err := db.IsEmpty("data"); err != nil || empty {
+		t.Fatalf("after Set: %v, %v", empty, err)
+	}
+
+	if err := db.Delete("data", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if empty, err := db.IsEmpty("data"); err != nil || !empty {
+		t.Fatalf("emptied bucket: %v, %v", empty, err)
+	}
+}
+
+func TestModifyConcurrentIncrementsAreNotLost(t *testing.T) {
+	db := newTestDB(t, nil)
+	const workers, perWorker = 8, 10
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				err := db.Modify("counters", "hits", func(current []byte) ([]byte, error) {
+					n, _ := strconv.Atoi(string(current))
+					return []byte(strconv.Itoa(n + 1)), nil
+				})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, err := db.Get("counters", "hits"); err != nil || string(v) != strconv.Itoa(workers*perWorker) {
+		t.Fatalf("got %q, %v, want %d", v, err, workers*perWorker)
+	}
+}
+
+func TestModifyErrorAbortsAndNilDeletes(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "data", "k", []byte("v"))
+	errAbort := errors.New("abort")
+
+	err := db.Modify("data", "k", func(current []byte) ([]byte, error) {
+		return []byte("changed"), errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("got %v, want errAbort", err)
+	}
+	if v, _ := db.Get("data", "k"); string(v) != "v" {
+		t.Fatalf("aborted Modify wrote %q", v)
+	}
+
+	if err := db.Modify("data", "k", func([]byte) ([]byte, error) { return nil, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := db.Get("data", "k"); v != nil {
+		t.Fatalf("key not deleted, holds %q", v)
+	}
+}
+
+func TestRecentNReturnsNewestFirst(t *testing.T) {
+	db := newTestDB(t, nil)
+	for i := 1; i <= 5; i++ {
+		mustSet(t, db, "feed", fmt.Sprintf("%08d", i), []byte(fmt.Sprint(i)))
+	}
+
+	keysOf := func(entries []KeyValue) string {
+		var keys []string
+		for _, e := range entries {
+			keys = append(keys, string(e.Value))
+		}
+		return strings.Join(keys, ",")
+	}
+	for n, want := range map[int]string{0: "", 3: "5,4,3", 5: "5,4,3,2,1", 10: "5,4,3,2,1"} {
+		entries, err := db.RecentN("feed", n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keysOf(entries); got != want {
+			t.Fatalf("RecentN(%d) = %q, want %q", n, got, want)
+		}
+	}
+
+	if entries, err := db.RecentN("missing", 3); err != nil || len(entries) != 0 {
+		t.Fatalf("missing bucket: %v, %v", entries, err)
+	}
+}
+
+func TestSetWithCommitHookRunsOnlyAfterCommit(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	ran := false
+	if err := db.SetWithCommitHook("data", "k", []byte("v"), func() { ran = true }); err != nil {
+		t.Fatal(err)
+	}
+	if !ran {
+		t.Fatal("hook did not run after a successful commit")
+	}
+
+	ran = false
+	if err := db.SetWithCommitHook("data", "", []byte("v"), func() { ran = true }); err == nil {
+		t.Fatal("expected an error for an empty key")
+	}
+	if ran {
+		t.Fatal("hook ran for a transaction that rolled back")
+	}
+}
+
+func TestRotateArchivesActiveBucket(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "log", "1", []byte("first"))
+	mustSet(t, db, "log", "2", []byte("second"))
+
+	if err := db.Rotate("log", "log.2026-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(bucketContents(t, db, "log.2026-01-01")); got != "map[1:first 2:second]" {
+		t.Fatalf("archive holds %s", got)
+	}
+	if empty, err := db.IsEmpty("log"); err != nil || !empty {
+		t.Fatalf("active bucket not empty: %v, %v", empty, err)
+	}
+
+	mustSet(t, db, "log", "3", []byte("third"))
+	if err := db.Rotate("log", "log.2026-01-01"); err == nil {
+		t.Fatal("expected an error rotating into an existing archive")
+	}
+	if v, _ := db.Get("log", "3"); string(v) != "third" {
+		t.Fatalf("failed rotation changed the active bucket, got %q", v)
+	}
+}
+
+func TestSetIfChangedSkipsIdenticalWrites(t *testing.T) {
+	db := newTestDB(t, nil)
+	writes := func(fn func()) int {
+		before := db.db.Load().Stats()
+		fn()
+		after := db.db.Load().Stats()
+		return after.Sub(&before).TxStats.Write
+	}
+	set := func(value string, want bool) {
+		t.Helper()
+		changed, err := db.SetIfChanged("data", "k", []byte(value))
+		if err != nil || changed != want {
+			t.Fatalf("SetIfChanged(%q) = %v, %v; want %v", value, changed, err, want)
+		}
+	}
+
+	changedWrites := writes(func() { set("v1", true) })
+	// Bolt still rewrites the freelist and meta page on commit, but no data pages.
+	for i := 0; i < 3; i++ {
+		if n := writes(func() { set("v1", false) }); n >= changedWrites {
+			t.Fatalf("identical write performed %d page writes, a real write %d", n, changedWrites)
+		}
+	}
+	set("v2", true)
+	if v, _ := db.Get("data", "k"); string(v) != "v2" {
+		t.Fatalf("got %q, want v2", v)
+	}
+}
+
+func TestGroupByPrefix(t *testing.T) {
+	db := newTestDB(t, nil)
+	for _, k := range []string{"a:1", "b:1", "a:2", "plain", "a:2:x"} {
+		mustSet(t, db, "data", k, []byte("v"))
+	}
+
+	groups, err := db.GroupByPrefix("data", ":")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(groups), "map[:[plain] a:[a:1 a:2 a:2:x] b:[b:1]]"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestDescribeSchemaLimitsSamples(t *testing.T) {
+	db := newTestDB(t, nil)
+	for i := 0; i < 5; i++ {
+		mustSet(t, db, "big", fmt.Sprintf("k%d", i), []byte("v"))
+	}
+	mustSet(t, db, "small", "only", []byte("v"))
+	if err := db.SetMetadata("big", "k0", []byte("m")); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := db.DescribeSchema(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema) != 2 {
+		t.Fatalf("described %d buckets, want big and small only", len(schema))
+	}
+	if got := len(schema["big"]); got != 3 {
+		t.Fatalf("big: %d samples, want 3", got)
+	}
+	if got := schema["small"]; len(got) != 1 || got[0].Key != "only" {
+		t.Fatalf("small: %v", got)
+	}
+}
+
+func TestCheckReferencesReportsDanglingOnly(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "users", "alice", []byte("v"))
+	mustSet(t, db, "users", "bob", []byte("v"))
+	mustSet(t, db, "orders", "o1", []byte("alice"))
+	mustSet(t, db, "orders", "o2", []byte("carol"))
+	mustSet(t, db, "orders", "o3", []byte("bob"))
+	mustSet(t, db, "orders", "o4", []byte(""))
+	mustSet(t, db, "orders", "o5", []byte("dave"))
+
+	dangling, err := db.CheckReferences("orders", "users", func(_ string, value []byte) (string, bool) {
+		return string(value), len(value) > 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(dangling, ","); got != "o2,o5" {
+		t.Fatalf("dangling = %q, want o2,o5", got)
+	}
+}
+
+func TestRenameBucketPrefix(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "old_users", "alice", []byte("1"))
+	mustSet(t, db, "old_orders", "o1", []byte("2"))
+	mustSet(t, db, "other", "k", []byte("3"))
+
+	n, err := db.RenameBucketPrefix("old_", "new_")
+	if err != nil || n != 2 {
+		t.Fatalf("RenameBucketPrefix = %d, %v; want 2", n, err)
+	}
+	if got := strings.Join(bucketNames(t, db), ","); got != "new_orders,new_users,other" {
+		t.Fatalf("buckets after rename: %s", got)
+	}
+	if v, _ := db.Get("new_users", "alice"); string(v) != "1" {
+		t.Fatalf("new_users/alice = %q", v)
+	}
+	if v, _ := db.Get("other", "k"); string(v) != "3" {
+		t.Fatalf("other/k = %q", v)
+	}
+
+	mustSet(t, db, "new_x", "k", []byte("v"))
+	mustSet(t, db, "old_x", "k", []byte("v"))
+	mustSet(t, db, "old_y", "k", []byte("v"))
+	if _, err := db.RenameBucketPrefix("old_", "new_"); err == nil {
+		t.Fatal("expected an error for a colliding target")
+	}
+	if got := strings.Join(bucketNames(t, db), ","); got != "new_orders,new_users,new_x,old_x,old_y,other" {
+		t.Fatalf("failed rename changed buckets: %s", got)
+	}
+}
+
+func TestNewBoltDatabaseReturnsOpenError(t *testing.T) {
+	db, err := NewBoltDatabase(filepath.Join(t.TempDir(), "missing", "test.db"))
+	if err == nil || db != nil {
+		t.Fatalf("NewBoltDatabase = %v, %v; want nil and an error", db, err)
+	}
+}
+
+func TestNewBoltDatabaseWithOptionsTimesOutOnLockedFile(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	locked, err := NewBoltDatabaseWithOptions(db.dbPath, 0600, &bolt.Options{Timeout: 50 * time.Millisecond})
+	if err != bolt.ErrTimeout || locked != nil {
+		t.Fatalf("second open = %v, %v; want ErrTimeout", locked, err)
+	}
+}
+
+func TestBucketsContainingKey(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "shard-b", "user:1", []byte("v"))
+	mustSet(t, db, "shard-a", "user:1", []byte("v"))
+	mustSet(t, db, "shard-c", "user:2", []byte("v"))
+	if err := db.SetMetadata("shard-a", "user:1", []byte("m")); err != nil {
+		t.Fatal(err)
+	}
+
+	buckets, err := db.BucketsContainingKey("user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(buckets, ","); got != "shard-a,shard-b" {
+		t.Fatalf("got %q, want shard-a,shard-b", got)
+	}
+}
+
+func TestExistsDistinguishesEmptyValuesFromMissingKeys(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "data", "empty", []byte{})
+	mustSet(t, db, "data", "full", []byte("v"))
+	err := db.db.Load().Update(func(tx *bolt.Tx) error {
+		_, err := tx.Bucket([]byte("data")).CreateBucket([]byte("nested"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewBoltDBWrapper(db, "data")
+	for key, want := range map[string]bool{"empty": true, "full": true, "missing": false, "nested": false, "emp": false} {
+		if got, err := w.Exists(key); err != nil || got != want {
+			t.Errorf("Exists(%q) = %v, %v; want %v", key, got, err, want)
+		}
+	}
+	if got, err := db.Exists("missing", "k"); err != nil || got {
+		t.Fatalf("missing bucket: %v, %v", got, err)
+	}
+}
+
+func TestCount(t *testing.T) {
+	db := newTestDB(t, nil)
+	keys := []string{"a", "b", "c"}
+	for _, k := range keys {
+		mustSet(t, db, "data", k, []byte("v"))
+	}
+	err := db.db.Load().Update(func(tx *bolt.Tx) error {
+		nested, err := tx.Bucket([]byte("data")).CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		return nested.Put([]byte("inner"), []byte("v"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := NewBoltDBWrapper(db, "data").Count(); err != nil || n != len(keys) {
+		t.Fatalf("Count = %d, %v; want %d", n, err, len(keys))
+	}
+	if n, err := db.Count("missing"); err != nil || n != 0 {
+		t.Fatalf("missing bucket: %d, %v", n, err)
+	}
+}
+
+func TestBatchGetOmitsMissingKeys(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "data", "a", []byte("1"))
+	mustSet(t, db, "data", "empty", []byte{})
+	mustSet(t, db, "data", "c", []byte("3"))
+
+	got, err := NewBoltDBWrapper(db, "data").BatchGet([]string{"c", "missing", "a", "empty"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || string(got["a"]) != "1" || string(got["c"]) != "3" {
+		t.Fatalf("BatchGet = %q", got)
+	}
+	if v, ok := got["empty"]; !ok || len(v) != 0 {
+		t.Fatalf("empty value = %q, %v", v, ok)
+	}
+	if _, ok := got["missing"]; ok {
+		t.Fatal("missing key present in result")
+	}
+}
+
+func TestDeleteBucketRemovesBucketAndSidecars(t *testing.T) {
+	db := newTestDB(t, nil)
+	db.SetChunkSize(4)
+	mustSet(t, db, "tenant", "big", []byte("0123456789"))
+	mustSet(t, db, "other", "k", []byte("v"))
+	if err := db.SetMetadata("tenant", "big", []byte("m")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.DeleteBucket("tenant"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(bucketNames(t, db), ","); got != "other" {
+		t.Fatalf("buckets after delete: %s", got)
+	}
+	if err := db.DeleteBucket("tenant"); !errors.Is(err, bolt.ErrBucketNotFound) {
+		t.Fatalf("second delete = %v, want ErrBucketNotFound", err)
+	}
+}
+
+func TestCreateBucketAndHasBucket(t *testing.T) {
+	db := newTestDB(t, &Options{MaxBuckets: 1})
+	if ok, err := db.HasBucket("tenant"); err != nil || ok {
+		t.Fatalf("HasBucket before create = %v, %v", ok, err)
+	}
+	if err := db.CreateBucket("tenant"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := db.HasBucket("tenant"); err != nil || !ok {
+		t.Fatalf("HasBucket after create = %v, %v", ok, err)
+	}
+	if err := db.CreateBucket("tenant"); !errors.Is(err, bolt.ErrBucketExists) {
+		t.Fatalf("second create = %v, want ErrBucketExists", err)
+	}
+	if err := db.CreateBucket("another"); !errors.Is(err, ErrTooManyBuckets) {
+		t.Fatalf("create over the cap = %v, want ErrTooManyBuckets", err)
+	}
+}
+
+func TestCollisionCheckReportsExistingKeys(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "data", "a", []byte("1"))
+	mustSet(t, db, "data", "empty", []byte{})
+	mustSet(t, db, "data", "c", []byte("3"))
+
+	collisions, err := db.CollisionCheck("data", []string{"new1", "c", "empty", "new2", "a", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(collisions, ","); got != "c,empty,a" {
+		t.Fatalf("collisions = %q, want c,empty,a", got)
+	}
+	if collisions, err := db.CollisionCheck("missing", []string{"a"}); err != nil || len(collisions) != 0 {
+		t.Fatalf("missing bucket: %v, %v", collisions, err)
+	}
+}
+
+func TestBucketsDistinguishesEmptyFromFailure(t *testing.T) {
+	db := newTestDB(t, nil)
+	if names, err := db.Buckets(); err != nil || names == nil || len(names) != 0 {
+		t.Fatalf("empty database: %#v, %v", names, err)
+	}
+	if err := db.db.Load().Close(); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := db.Buckets(); err == nil || names != nil {
+		t.Fatalf("closed database: %#v, %v; want an error", names, err)
+	}
+}
+
+func TestUpdateRollsBackOnErrorAndViewSkipsMissingBuckets(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "counters", "hits", []byte("1"))
+
+	failure := errors.New("abort")
+	err := db.Update("counters", func(tx *bolt.Tx, bucket *bolt.Bucket) error {
+		if err := bucket.Put([]byte("hits"), []byte("2")); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("Update error = %v, want %v", err, failure)
+	}
+	if got := bucketContents(t, db, "counters"); got["hits"] != "1" {
+		t.Fatalf("hits = %q after a failed Update, want 1", got["hits"])
+	}
+
+	called := false
+	if err := db.View("missing", func(*bolt.Bucket) error { called = true; return nil }); err != nil || called {
+		t.Fatalf("View of a missing bucket: called=%v, err=%v", called, err)
+	}
+}
+
+func TestValueSizeStats(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "blobs", "a", []byte("x"))
+	mustSet(t, db, "blobs", "b", []byte("xxxx"))
+	mustSet(t, db, "blobs", "c", []byte{})
+
+	count, total, max, err := db.ValueSizeStats("blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || total != 5 || max != 4 {
+		t.Fatalf("ValueSizeStats = %d, %d, %d; want 3, 5, 4", count, total, max)
+	}
+
+	count, total, max, err = db.ValueSizeStats("missing")
+	if err != nil || count != 0 || total != 0 || max != 0 {
+		t.Fatalf("missing bucket = %d, %d, %d, %v", count, total, max, err)
+	}
+}
+
+func TestDeleteWhereRemovesOnlyMatchingEntries(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "sessions", "a", []byte("stale"))
+	mustSet(t, db, "sessions", "b", []byte("fresh"))
+	mustSet(t, db, "sessions", "c", []byte("stale"))
+
+	n, err := db.DeleteWhere("sessions", func(_, value []byte) bool { return string(value) == "stale" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("deleted %d entries, want 2", n)
+	}
+	got := bucketContents(t, db, "sessions")
+	if len(got) != 1 || got["b"] != "fresh" {
+		t.Fatalf("remaining entries = %v, want only b", got)
+	}
+
+	if n, err := db.DeleteWhere("missing", func(_, _ []byte) bool { return true }); err != nil || n != 0 {
+		t.Fatalf("missing bucket = %d, %v", n, err)
+	}
+}
+
+func TestValidateReportsOnlyInvalidEntries(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "docs", "good", []byte(`{"a":1}`))
+	mustSet(t, db, "docs", "bad1", []byte(`{"a":`))
+	mustSet(t, db, "docs", "bad2", []byte("plain"))
+
+	failures, err := db.Validate("docs", func(_, value []byte) error {
+		var v any
+		return json.Unmarshal(value, &v)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 2 || failures[0].Key != "bad1" || failures[1].Key != "bad2" {
+		t.Fatalf("failures = %v, want bad1 and bad2", failures)
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(failures[1], &syntaxErr) {
+		t.Fatalf("failure %v does not unwrap to a JSON syntax error", failures[1])
+	}
+}
+
+func TestDistinctValuePrefixes(t *testing.T) {
+	db := newTestDB(t, nil)
+	for k, v := range map[string]string{"1": "abc", "2": "abd", "3": "b", "4": "xyz"} {
+		mustSet(t, db, "values", k, []byte(v))
+	}
+
+	got, err := db.DistinctValuePrefixes("values", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"6162": 2, "62": 1, "7879": 1}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("prefixes = %v, want %v", got, want)
+	}
+	if _, err := db.DistinctValuePrefixes("values", 0); err == nil {
+		t.Fatal("DistinctValuePrefixes accepted a zero prefix length")
+	}
+}
+
+func TestRenameBucketPrefixCarriesSidecarsAndSequences(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "old-a", "k", []byte("v"))
+	db.SetChunkSize(4)
+	mustSet(t, db, "old-b", "big", []byte("chunked value"))
+	db.SetChunkSize(0)
+	if _, _, err := db.ReserveIDs("old-a", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetMetadata("old-a", "k", []byte("m")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.RenameBucketPrefix("", "x"); err == nil {
+		t.Fatal("RenameBucketPrefix with an empty prefix succeeded")
+	}
+
+	n, err := db.RenameBucketPrefix("old-", "new-")
+	if err != nil || n != 2 {
+		t.Fatalf("RenameBucketPrefix = %d, %v; want 2, nil", n, err)
+	}
+	if got, err := db.Get("new-b", "big"); err != nil || string(got) != "chunked value" {
+		t.Fatalf("Get chunked value after rename = %q, %v", got, err)
+	}
+	if got, err := db.GetMetadata("new-a", "k"); err != nil || string(got) != "m" {
+		t.Fatalf("GetMetadata after rename = %q, %v", got, err)
+	}
+	if start, _, err := db.ReserveIDs("new-a", 1); err != nil || start != 6 {
+		t.Fatalf("ReserveIDs after rename = %d, %v; want 6", start, err)
+	}
+
+	for _, name := range bucketNames(t, db) {
+		if strings.Contains(name, "old-") {
+			t.Fatalf("bucket %s left behind by rename", name)
+		}
+	}
+}
+
+func TestRenameBucketPrefixSkipsReservedBuckets(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "a", "k", []byte("v"))
+	if err := db.SetMetadata("a", "k", []byte("m")); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := db.RenameBucketPrefix("_", "x")
+	if err != nil || n != 0 {
+		t.Fatalf("RenameBucketPrefix(\"_\") = %d, %v; want 0, nil", n, err)
+	}
+	if got, err := db.GetMetadata("a", "k"); err != nil || string(got) != "m" {
+		t.Fatalf("GetMetadata = %q, %v; reserved bucket was renamed", got, err)
+	}
+}
+
+func TestCheckpointAndRotateKeepSequences(t *testing.T) {
+	db := newTestDB(t, nil)
+	if _, _, err := db.ReserveIDs("log", 3); err != nil {
+		t.Fatal(err)
+	}
+	restore, err := db.Checkpoint("log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := db.ReserveIDs("log", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if start, _, err := db.ReserveIDs("log", 1); err != nil || start != 4 {
+		t.Fatalf("ReserveIDs after restore = %d, %v; want 4", start, err)
+	}
+
+	if err := db.Rotate("log", "log-archive"); err != nil {
+		t.Fatal(err)
+	}
+	if start, _, err := db.ReserveIDs("log", 1); err != nil || start != 5 {
+		t.Fatalf("ReserveIDs after rotate = %d, %v; want 5", start, err)
+	}
+	if start, _, err := db.ReserveIDs("log-archive", 1); err != nil || start != 5 {
+		t.Fatalf("ReserveIDs on archive = %d, %v; want 5", start, err)
+	}
+}
+
+func TestSetIfChangedSkipsWriteWhenUnchanged(t *testing.T) {
+	db := newTestDB(t, nil)
+	db.SetChunkSize(4)
+
+	changed, err := db.SetIfChanged("b", "k", []byte("chunked value"))
+	if err != nil || !changed {
+		t.Fatalf("first SetIfChanged = %v, %v; want true", changed, err)
+	}
+
+	writes := db.db.Load().Stats().TxStats.Write
+	changed, err = db.SetIfChanged("b", "k", []byte("chunked value"))
+	if err != nil || changed {
+		t.Fatalf("SetIfChanged with same value = %v, %v; want false", changed, err)
+	}
+	if got := db.db.Load().Stats().TxStats.Write; got != writes {
+		t.Fatalf("unchanged SetIfChanged performed %d writes", got-writes)
+	}
+
+	changed, err = db.SetIfChanged("b", "k", []byte("other"))
+	if err != nil || !changed {
+		t.Fatalf("SetIfChanged with new value = %v, %v; want true", changed, err)
+	}
+	if got, err := db.Get("b", "k"); err != nil || string(got) != "other" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+}
+
+func TestSetWithCommitHookMayWriteWhileQuiescePending(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- db.SetWithCommitHook("data", "k", []byte("v"), func() {
+			// A Quiesce queued behind the hook must not block the hook's own write.
+			quiesced := make(chan struct{})
+			go func() {
+				db.Quiesce()
+				close(quiesced)
+				db.Resume()
+			}()
+			time.Sleep(20 * time.Millisecond)
+			if err := db.Set("data", "hook", []byte("ran")); err != nil {
+				t.Errorf("Set from commit hook: %v", err)
+			}
+			<-quiesced
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SetWithCommitHook deadlocked with a hook that writes")
+	}
+	if got := bucketContents(t, db, "data"); got["hook"] != "ran" {
+		t.Fatalf("hook write = %q, want ran", got["hook"])
+	}
+}
+
+func TestCheckReferencesTreatsEmptyValuesAsPresent(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "users", "alice", []byte{})
+	err := db.Update("users", func(_ *bolt.Tx, bucket *bolt.Bucket) error {
+		_, err := bucket.CreateBucket([]byte("nested"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, db, "orders", "o1", []byte("alice"))
+	mustSet(t, db, "orders", "o2", []byte("nested"))
+	mustSet(t, db, "orders", "o3", []byte{})
+
+	dangling, err := db.CheckReferences("orders", "users", func(key string, value []byte) (string, bool) {
+		if key == "o3" {
+			return "missing", true
+		}
+		return string(value), true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(dangling, ","); got != "o2,o3" {
+		t.Fatalf("dangling = %q, want o2,o3", got)
+	}
+}
+
+func TestBucketsContainingKeyCountsEmptyValuesButNotNestedBuckets(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "empty", "k", []byte{})
+	err := db.Update("nested", func(_ *bolt.Tx, bucket *bolt.Bucket) error {
+		_, err := bucket.CreateBucket([]byte("k"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buckets, err := db.BucketsContainingKey("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(buckets, ","); got != "empty" {
+		t.Fatalf("got %q, want empty", got)
+	}
+}
@@ -0,0 +1,147 @@
+package boltdb
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// BufferedWriter queues write operations and commits them in batches, either when
+// MaxOps operations are queued or when MaxInterval has passed since the first queued
+// operation, whichever comes first.
+// It is safe for concurrent use; timer flushes and writes are serialized by a mutex.
+type BufferedWriter struct {
+	lck         sync.Mutex
+	db          *BoltDatabase
+	batch       *BoltBatch    // Operations queued since the last flush
+	pending     int           // Number of queued operations
+	maxOps      int           // Flush when this many operations are queued, 0 to disable
+	maxInterval time.Duration // Flush this long after the first queued operation, 0 to disable
+	timer       *time.Timer   // Pending interval flush, nil when idle
+	timerGen    int           // Incremented per timer so stale timer callbacks are ignored
+	timerErr    error         // Error from the last interval flush, reported by the next call
+	closed      bool
+}
+
+// NewBufferedWriter creates a buffered writer for the specified database.
+//
+// Parameters:
+//   - db: The database to write to
+//   - maxOps: The number of queued operations that triggers a flush, 0 to disable
+//   - maxInterval: The longest time an operation may stay queued, 0 to disable
+//
+// Returns:
+//   - *BufferedWriter: A new buffered writer
+func NewBufferedWriter(db *BoltDatabase, maxOps int, maxInterval time.Duration) *BufferedWriter {
+	return &BufferedWriter{
+		db:          db,
+		batch:       db.NewBatch(),
+		maxOps:      maxOps,
+		maxInterval: maxInterval,
+	}
+}
+
+// Write queues an operation, flushing if the count threshold is reached.
+// An error from a previous interval flush is returned by the next Write.
+//
+// Parameters:
+//   - op: The write operation to queue
+//
+// Returns:
+//   - error: An error if the writer is closed, queueing fails, or a flush fails
+func (w *BufferedWriter) Write(op *WriteOperation) error {
+	w.lck.Lock()
+	defer w.lck.Unlock()
+	if w.closed {
+		return errors.New("buffered writer is closed")
+	}
+	if err := w.takeTimerErr(); err != nil {
+		return err
+	}
+
+	if err := w.batch.Add(op); err != nil {
+		return err
+	}
+	w.pending++
+	if w.maxOps > 0 && w.pending >= w.maxOps {
+		return w.flushLocked()
+	}
+	if w.maxInterval > 0 && w.timer == nil {
+		w.timerGen++
+		gen := w.timerGen
+		w.timer = time.AfterFunc(w.maxInterval, func() {
+			w.intervalFlush(gen)
+		})
+	}
+	return nil
+}
+
+// Flush commits all queued operations. If the commit fails, the operations stay queued
+// and are retried by the next flush, including a Flush after a failed Close.
+//
+// Returns:
+//   - error: Any error from this flush or from a previous interval flush
+func (w *BufferedWriter) Flush() error {
+	w.lck.Lock()
+	defer w.lck.Unlock()
+	if err := w.takeTimerErr(); err != nil {
+		return err
+	}
+	return w.flushLocked()
+}
+
+// Close stops the interval timer and flushes any queued operations.
+// Further writes fail after Close.
+//
+// Returns:
+//   - error: Any error from the final flush or a previous interval flush
+func (w *BufferedWriter) Close() error {
+	w.lck.Lock()
+	defer w.lck.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return errors.Join(w.takeTimerErr(), w.flushLocked())
+}
+
+// intervalFlush runs on the timer goroutine when MaxInterval expires.
+// It does nothing if the timer was superseded by a flush while waiting for the lock.
+func (w *BufferedWriter) intervalFlush(gen int) {
+	w.lck.Lock()
+	defer w.lck.Unlock()
+	if w.timer == nil || gen != w.timerGen {
+		return
+	}
+	w.timer = nil
+	if err := w.flushLocked(); err != nil {
+		w.timerErr = err
+	}
+}
+
+// flushLocked commits the queued operations. The caller must hold the lock.
+// On failure the operations stay queued and are retried by the next flush; buckets
+// that did commit are written again, which leaves the same result.
+func (w *BufferedWriter) flushLocked() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.pending == 0 {
+		return nil
+	}
+
+	if err := w.batch.Execute(); err != nil {
+		return err
+	}
+	w.batch = w.db.NewBatch()
+	w.pending = 0
+	return nil
+}
+
+// takeTimerErr returns and clears the error of the last interval flush.
+func (w *BufferedWriter) takeTimerErr() error {
+	err := w.timerErr
+	w.timerErr = nil
+	return err
+}
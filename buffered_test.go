@@ -0,0 +1,109 @@
+package boltdb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBufferedWriterFlushesAfterInterval(t *testing.T) {
+	db := newTestDB(t, nil)
+	w := NewBufferedWriter(db, 100, 20*time.Millisecond)
+	defer w.Close()
+
+	if err := w.Write(NewSetOp("data", "k", []byte("v"))); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := db.Get("data", "k"); v != nil {
+		t.Fatal("operation was committed before the interval expired")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if v, err := db.Get("data", "k"); err == nil && string(v) == "v" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("operation was not flushed after the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBufferedWriterFlushesAtMaxOps(t *testing.T) {
+	db := newTestDB(t, nil)
+	w := NewBufferedWriter(db, 2, 0)
+
+	if err := w.Write(NewSetOp("a", "k", []byte("1"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(NewSetOp("b", "k", []byte("2"))); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := db.Get("b", "k"); string(v) != "2" {
+		t.Fatalf("reaching maxOps did not flush, got %q", v)
+	}
+
+	if err := w.Write(NewSetOp("c", "k", []byte("3"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := db.Get("c", "k"); string(v) != "3" {
+		t.Fatalf("Close did not flush, got %q", v)
+	}
+	if err := w.Write(NewSetOp("d", "k", []byte("4"))); err == nil {
+		t.Fatal("Write after Close succeeded")
+	}
+}
+
+func TestBufferedWriterKeepsOpsOnFailedFlush(t *testing.T) {
+	db := newTestDB(t, &Options{MaxBuckets: 1})
+	mustSet(t, db, "a", "k", []byte("v"))
+
+	w := NewBufferedWriter(db, 0, 0)
+	if err := w.Write(NewSetOp("b", "k", []byte("queued"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); !errors.Is(err, ErrTooManyBuckets) {
+		t.Fatalf("Flush = %v, want ErrTooManyBuckets", err)
+	}
+
+	if err := db.DeleteBucket("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush after freeing a bucket = %v", err)
+	}
+	if got, err := db.Get("b", "k"); err != nil || string(got) != "queued" {
+		t.Fatalf("Get after retried flush = %q, %v", got, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBufferedWriterIntervalFlushFailure(t *testing.T) {
+	db := newTestDB(t, &Options{MaxBuckets: 1})
+	mustSet(t, db, "a", "k", []byte("v"))
+
+	w := NewBufferedWriter(db, 0, 10*time.Millisecond)
+	if err := w.Write(NewSetOp("b", "k", []byte("queued"))); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := w.Write(NewSetOp("b", "k2", []byte("v"))); !errors.Is(err, ErrTooManyBuckets) {
+		t.Fatalf("Write after failed interval flush = %v, want ErrTooManyBuckets", err)
+	}
+	if err := db.DeleteBucket("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	if got, err := db.Get("b", "k"); err != nil || string(got) != "queued" {
+		t.Fatalf("Get after Close = %q, %v", got, err)
+	}
+}
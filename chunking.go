@@ -0,0 +1,139 @@
+package boltdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+
+	"github.com/boltdb/bolt"
+)
+
+// chunkBucketPrefix prefixes the reserved buckets holding the chunks of large values.
+// The chunks of key "k" in bucket "files" live in "__chunks__:files" as "k#0", "k#1", ...
+const chunkBucketPrefix = "__chunks__:"
+
+// chunkManifestMagic starts the manifest stored under the key of a chunked value.
+// It is followed by the chunk count and total size as uvarints.
+var chunkManifestMagic = []byte("\x00\xffbolt-chunks\x00")
+
+// SetChunkSize enables automatic chunking of large values.
+// Writes split values larger than n bytes across a reserved sidecar bucket and store a
+// small manifest under the key. Every read, write and iteration method reassembles or
+// removes the chunks transparently; only the bolt buckets handed out by Update and View
+// expose the manifest. A value of zero or less disables chunking for new writes; values
+// already chunked are still reassembled.
+//
+// Parameters:
+//   - n: The chunk size in bytes
+func (b *BoltDatabase) SetChunkSize(n int) {
+	b.chunkSize = n
+}
+
+// putValue stores value under key, chunking it when it exceeds the chunk size.
+// Chunks of a previously stored value are removed first, in the same transaction.
+func (b *BoltDatabase) putValue(tx *bolt.Tx, bucket *bolt.Bucket, bucketName, key string, value []byte) error {
+	if err := b.deleteChunks(tx, bucketName, key, bucket.Get([]byte(key))); err != nil {
+		return err
+	}
+	if b.chunkSize <= 0 || len(value) <= b.chunkSize {
+		return bucket.Put([]byte(key), value)
+	}
+
+	chunks, err := tx.CreateBucketIfNotExists([]byte(chunkBucketPrefix + bucketName))
+	if err != nil {
+		return err
+	}
+	count := 0
+	for off := 0; off < len(value); off += b.chunkSize {
+		end := min(off+b.chunkSize, len(value))
+		if err := chunks.Put(chunkKey(key, count), value[off:end]); err != nil {
+			return err
+		}
+		count++
+	}
+
+	manifest := append([]byte(nil), chunkManifestMagic...)
+	manifest = binary.AppendUvarint(manifest, uint64(count))
+	manifest = binary.AppendUvarint(manifest, uint64(len(value)))
+	return bucket.Put([]byte(key), manifest)
+}
+
+// readValue returns the logical value for a stored value, reassembling it from its
+// chunks when stored is a manifest.
+func (b *BoltDatabase) readValue(tx *bolt.Tx, bucketName, key string, stored []byte) ([]byte, error) {
+	count, size, ok := parseChunkManifest(stored)
+	if !ok {
+		return stored, nil
+	}
+	chunks := tx.Bucket([]byte(chunkBucketPrefix + bucketName))
+	if chunks == nil {
+		return nil, errors.New("chunk bucket not found")
+	}
+	value := make([]byte, 0, size)
+	for i := 0; i < count; i++ {
+		chunk := chunks.Get(chunkKey(key, i))
+		if chunk == nil {
+			return nil, errors.New("missing chunk " + strconv.Itoa(i) + " of key " + key)
+		}
+		value = append(value, chunk...)
+	}
+	return value, nil
+}
+
+// entryValue returns the logical value of an entry read from bucketName by iteration,
+// reassembling it when it is chunked. The key is only converted for chunked values.
+func (b *BoltDatabase) entryValue(tx *bolt.Tx, bucketName string, k, v []byte) ([]byte, error) {
+	if !bytes.HasPrefix(v, chunkManifestMagic) {
+		return v, nil
+	}
+	return b.readValue(tx, bucketName, string(k), v)
+}
+
+// valueSize returns the logical size of a stored value, the full size for chunked values.
+func valueSize(stored []byte) int {
+	if _, size, ok := parseChunkManifest(stored); ok {
+		return size
+	}
+	return len(stored)
+}
+
+// deleteChunks removes the chunks referenced by stored, if it is a manifest.
+func (b *BoltDatabase) deleteChunks(tx *bolt.Tx, bucketName, key string, stored []byte) error {
+	count, _, ok := parseChunkManifest(stored)
+	if !ok {
+		return nil
+	}
+	chunks := tx.Bucket([]byte(chunkBucketPrefix + bucketName))
+	if chunks == nil {
+		return nil
+	}
+	for i := 0; i < count; i++ {
+		if err := chunks.Delete(chunkKey(key, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseChunkManifest decodes a chunk manifest, reporting false if stored is not one.
+func parseChunkManifest(stored []byte) (count, size int, ok bool) {
+	rest, found := bytes.CutPrefix(stored, chunkManifestMagic)
+	if !found {
+		return 0, 0, false
+	}
+	c, n := binary.Uvarint(rest)
+	if n <= 0 {
+		return 0, 0, false
+	}
+	s, m := binary.Uvarint(rest[n:])
+	if m <= 0 || n+m != len(rest) {
+		return 0, 0, false
+	}
+	return int(c), int(s), true
+}
+
+// chunkKey returns the sidecar key of chunk i of key.
+func chunkKey(key string, i int) []byte {
+	return []byte(key + "#" + strconv.Itoa(i))
+}
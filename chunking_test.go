@@ -0,0 +1,207 @@
+package boltdb
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+// chunkCount returns the number of chunks stored for bucketName.
+func chunkCount(t *testing.T, db *BoltDatabase, bucketName string) int {
+	t.Helper()
+	return len(bucketContents(t, db, chunkBucketPrefix+bucketName))
+}
+
+func TestChunkedValueRoundTripAndDelete(t *testing.T) {
+	db := newTestDB(t, nil)
+	db.SetChunkSize(16)
+
+	large := bytes.Repeat([]byte("0123456789"), 10)
+	mustSet(t, db, "files", "big", large)
+	mustSet(t, db, "files", "small", []byte("tiny"))
+
+	if got, err := db.Get("files", "big"); err != nil || !bytes.Equal(got, large) {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+	if n := chunkCount(t, db, "files"); n != 7 {
+		t.Fatalf("stored %d chunks, want 7", n)
+	}
+
+	mustSet(t, db, "files", "big", large[:40])
+	if n := chunkCount(t, db, "files"); n != 3 {
+		t.Fatalf("overwrite left %d chunks, want 3", n)
+	}
+
+	if err := db.Delete("files", "big"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := db.Get("files", "big"); got != nil {
+		t.Fatalf("deleted value still readable: %q", got)
+	}
+	if n := chunkCount(t, db, "files"); n != 0 {
+		t.Fatalf("Delete left %d chunks", n)
+	}
+	if got, _ := db.Get("files", "small"); string(got) != "tiny" {
+		t.Fatalf("small = %q", got)
+	}
+}
+
+func TestChunkedValuesReadThroughEveryAPI(t *testing.T) {
+	db := newTestDB(t, nil)
+	db.SetChunkSize(8)
+	big := bytes.Repeat([]byte("0123456789"), 10)
+	mustSet(t, db, "b", "big", big)
+	if chunkCount(t, db, "b") == 0 {
+		t.Fatal("value larger than the chunk size was not chunked")
+	}
+
+	check := func(api string, value []byte) {
+		t.Helper()
+		if !bytes.Equal(value, big) {
+			t.Errorf("%s returned %q, want the logical value", api, value)
+		}
+	}
+	visit := func(api string) func(k, v []byte) error {
+		return func(k, v []byte) error {
+			check(api, v)
+			return nil
+		}
+	}
+
+	got, err := db.Get("b", "big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("Get", got)
+
+	all, err := db.List("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("List", all["big"])
+
+	batch, err := db.BatchGet("b", []string{"big"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("BatchGet", batch["big"])
+
+	if err := db.ForEach("b", visit("ForEach")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ScanPrefix("b", "bi", visit("ScanPrefix")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Range("b", "a", "c", visit("Range")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ForEachReverse("b", visit("ForEachReverse")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ListParallel("b", 2, visit("ListParallel")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, values, _, err := db.Page("b", "", 10)
+	if err != nil || len(values) != 1 {
+		t.Fatalf("Page = %d values, %v", len(values), err)
+	}
+	check("Page", values[0])
+
+	it, err := db.Iterator("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !it.Next() {
+		t.Fatalf("Iterator yielded nothing: %v", it.Err())
+	}
+	check("Iterator", it.Value())
+	if err := it.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Modify("b", "big", func(current []byte) ([]byte, error) {
+		check("Modify", current)
+		return current, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var indexed []byte
+	err = db.CreateIndex("b", "b-idx", func(key string, value []byte) string {
+		indexed = value
+		return "all"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("CreateIndex", indexed)
+}
+
+func TestChunkedValuesLeaveNoStaleChunks(t *testing.T) {
+	db := newTestDB(t, nil)
+	db.SetChunkSize(8)
+	mustSet(t, db, "b", "k", bytes.Repeat([]byte("x"), 64))
+	long := chunkCount(t, db, "b")
+
+	mustSet(t, db, "b", "k", bytes.Repeat([]byte("y"), 16))
+	if n := chunkCount(t, db, "b"); n == 0 || n >= long {
+		t.Fatalf("chunks after shorter overwrite = %d, had %d", n, long)
+	}
+
+	batch := db.NewBatch()
+	if err := batch.AddSet("b", "k", []byte("small")); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if n := chunkCount(t, db, "b"); n != 0 {
+		t.Fatalf("chunks after unchunked batch overwrite = %d, want 0", n)
+	}
+	if got, err := db.Get("b", "k"); err != nil || string(got) != "small" {
+		t.Fatalf("Get after batch overwrite = %q, %v", got, err)
+	}
+
+	mustSet(t, db, "b", "k", bytes.Repeat([]byte("z"), 64))
+	if err := db.Delete("b", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if n := chunkCount(t, db, "b"); n != 0 {
+		t.Fatalf("chunks after Delete = %d, want 0", n)
+	}
+}
+
+func TestMigrateReassemblesChunkedValues(t *testing.T) {
+	src := newTestDB(t, nil)
+	src.SetChunkSize(8)
+	big := bytes.Repeat([]byte("abc"), 20)
+	mustSet(t, src, "b", "big", big)
+
+	dst, err := Open(filepath.Join(t.TempDir(), "dst.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	var seen []byte
+	err = Migrate(src, dst, func(bucket, key string, value []byte) (string, []byte, bool) {
+		if bucket == "b" {
+			seen = append([]byte{}, value...)
+		}
+		return key, value, true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(seen, big) {
+		t.Fatalf("transform saw %q, want the logical value", seen)
+	}
+	if got, err := dst.Get("b", "big"); err != nil || !bytes.Equal(got, big) {
+		t.Fatalf("Get after Migrate = %q, %v", got, err)
+	}
+	if n := chunkCount(t, dst, "b"); n != 0 {
+		t.Fatalf("dst without chunking holds %d chunks, want 0", n)
+	}
+}
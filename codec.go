@@ -0,0 +1,68 @@
+package boltdb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec converts typed values to and from their stored byte form.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes values as JSON. It is the default codec for wrappers.
+type JSONCodec struct{}
+
+// Marshal encodes v as JSON.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON data into v.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec encodes values with encoding/gob.
+type GobCodec struct{}
+
+// Marshal encodes v with gob.
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes gob data into v.
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// SetBucketCodec sets the codec used by wrappers returned from Bucket for the named bucket.
+//
+// Parameters:
+//   - bucketName: The name of the bucket
+//   - codec: The codec for the bucket's values
+func (b *BoltDatabase) SetBucketCodec(bucketName string, codec Codec) {
+	b.codecs.Store(bucketName, codec)
+}
+
+// Bucket returns a wrapper for the named bucket, configured with the bucket's codec.
+// Buckets without a registered codec use JSONCodec.
+//
+// Parameters:
+//   - bucketName: The name of the bucket
+//
+// Returns:
+//   - *BoltDBWrapper: A wrapper for the bucket
+func (b *BoltDatabase) Bucket(bucketName string) *BoltDBWrapper {
+	w := NewBoltDBWrapper(b, bucketName)
+	if codec, ok := b.codecs.Load(bucketName); ok {
+		w.codec = codec.(Codec)
+	}
+	return w
+}
@@ -0,0 +1,47 @@
+package boltdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+type codecRecord struct {
+	Name  string
+	Count int
+}
+
+func TestBucketCodecsRoundTripPerBucket(t *testing.T) {
+	f := newTestFactory(t, "app")
+	f.RegisterBucketCodec("app", "events", JSONCodec{})
+	f.RegisterBucketCodec("app", "users", GobCodec{})
+	db, err := f.Get("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := codecRecord{Name: "alice", Count: 3}
+	for _, bucket := range []string{"events", "users"} {
+		w := db.Bucket(bucket)
+		if err := w.SetValue("k", want); err != nil {
+			t.Fatalf("%s: %v", bucket, err)
+		}
+		var got codecRecord
+		found, err := w.GetValue("k", &got)
+		if err != nil || !found || got != want {
+			t.Fatalf("%s: got %+v, %v, %v", bucket, got, found, err)
+		}
+	}
+
+	events, _ := db.Get("events", "k")
+	if !bytes.HasPrefix(events, []byte("{")) {
+		t.Fatalf("events value %q is not JSON", events)
+	}
+	users, _ := db.Get("users", "k")
+	if bytes.HasPrefix(users, []byte("{")) {
+		t.Fatalf("users value %q was not gob encoded", users)
+	}
+	var fromGob codecRecord
+	if err := (GobCodec{}).Unmarshal(users, &fromGob); err != nil || fromGob != want {
+		t.Fatalf("gob decode: %+v, %v", fromGob, err)
+	}
+}
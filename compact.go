@@ -0,0 +1,209 @@
+package boltdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/boltdb/bolt"
+)
+
+// FragmentationRatio returns the fraction of the database file occupied by free pages.
+// Bolt never shrinks its file on its own, so space released by deletes stays allocated
+// on disk until the database is compacted.
+//
+// Returns:
+//   - float64: Free page bytes divided by the file size, in the range [0, 1]
+//   - error: Any error that occurred while reading the file size
+func (b *BoltDatabase) FragmentationRatio() (float64, error) {
+	info, err := os.Stat(b.dbPath)
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() == 0 {
+		return 0, nil
+	}
+	stats := b.db.Load().Stats()
+	ratio := float64(stats.FreeAlloc) / float64(info.Size())
+	return min(ratio, 1), nil
+}
+
+// CompactInPlace rewrites the database into a fresh file and swaps it in place of the
+// current one, releasing the space held by free pages.
+// Writes are held off from the snapshot until the swap completes, so none are lost, and
+// it must therefore not be called between Quiesce and Resume. Reads that race with the
+// swap may fail with bolt.ErrDatabaseNotOpen, which AutoReopenOnError recovers from for
+// Get. Bucket sequences are preserved. If the swap fails after the database was closed,
+// the original file is put back and reopened before the error is returned.
+//
+// Returns:
+//   - error: ErrReadOnly if the database was opened read-only, or any error that
+//     occurred while copying, renaming or reopening the database
+func (b *BoltDatabase) CompactInPlace() error {
+	if b.readOnly {
+		return ErrReadOnly
+	}
+	b.writeGate.Lock()
+	defer b.writeGate.Unlock()
+	b.swapLck.Lock()
+	defer b.swapLck.Unlock()
+	if b.closed.Load() {
+		return bolt.ErrDatabaseNotOpen
+	}
+
+	current := b.db.Load()
+	tmpPath := b.dbPath + ".compact"
+	if err := compactTo(current, tmpPath, b.mode); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// A hard link keeps the original file reachable until the compacted copy is open,
+	// so a failed swap can put it back.
+	origPath := b.dbPath + ".orig"
+	os.Remove(origPath)
+	if err := os.Link(b.dbPath, origPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	defer os.Remove(origPath)
+
+	if err := current.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := renameFile(tmpPath, b.dbPath); err != nil {
+		os.Remove(tmpPath)
+		return b.reopenOriginal(origPath, err)
+	}
+
+	db, err := bolt.Open(b.dbPath, b.mode, b.boltOpts)
+	if err != nil {
+		return b.reopenOriginal(origPath, err)
+	}
+	b.db.Store(db)
+	b.applyOptions()
+	return nil
+}
+
+// renameFile renames a file; tests replace it to make the swap of CompactInPlace fail.
+var renameFile = os.Rename
+
+// reopenOriginal puts the original file saved at origPath back in place after a failed
+// compaction swap and reopens it, so the database stays usable. It returns cause, joined
+// with any error from restoring the original.
+func (b *BoltDatabase) reopenOriginal(origPath string, cause error) error {
+	if err := os.Rename(origPath, b.dbPath); err != nil {
+		return errors.Join(cause, err)
+	}
+	db, err := bolt.Open(b.dbPath, b.mode, b.boltOpts)
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+	b.db.Store(db)
+	b.applyOptions()
+	return cause
+}
+
+// CompactIfNeeded compacts the database only when its fragmentation ratio exceeds
+// the given threshold. It is intended to be called periodically from a scheduler.
+//
+// Parameters:
+//   - threshold: The fragmentation ratio above which compaction runs
+//
+// Returns:
+//   - bool: True if compaction ran
+//   - error: Any error that occurred while measuring or compacting
+func (b *BoltDatabase) CompactIfNeeded(threshold float64) (bool, error) {
+	ratio, err := b.FragmentationRatio()
+	if err != nil {
+		return false, err
+	}
+	if ratio <= threshold {
+		return false, nil
+	}
+	if err := b.CompactInPlace(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ErrPageSizeUnsupported is returned by RebuildWithPageSize for page sizes bolt cannot create.
+var ErrPageSizeUnsupported = errors.New("page size not supported")
+
+// RebuildWithPageSize copies all data into a new database file at destPath created with
+// the given page size, and returns the new database.
+// The bolt version this package builds on always creates files with the operating
+// system's page size and offers no option to choose another, so only that size is
+// accepted; any other power of two fails with ErrPageSizeUnsupported. The source
+// database is left untouched.
+//
+// Parameters:
+//   - destPath: The file path of the new database, which must not exist yet
+//   - pageSize: The page size of the new database, a power of two
+//
+// Returns:
+//   - *BoltDatabase: The new database
+//   - error: An error if pageSize is invalid or unsupported, or the copy fails
+func (b *BoltDatabase) RebuildWithPageSize(destPath string, pageSize int) (*BoltDatabase, error) {
+	if pageSize <= 0 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("page size %d is not a power of two", pageSize)
+	}
+	if pageSize != os.Getpagesize() {
+		return nil, fmt.Errorf("%w: %d, only the system page size %d can be created",
+			ErrPageSizeUnsupported, pageSize, os.Getpagesize())
+	}
+	if _, err := os.Stat(destPath); err == nil {
+		return nil, fmt.Errorf("destination %s already exists", destPath)
+	}
+
+	if err := compactTo(b.db.Load(), destPath, b.mode); err != nil {
+		os.Remove(destPath)
+		return nil, err
+	}
+	return NewBoltDatabaseWithOptions(destPath, b.mode, b.boltOpts)
+}
+
+// compactTo copies every bucket of src into a new database file at dstPath, created
+// with the given file mode.
+func compactTo(src *bolt.DB, dstPath string, mode os.FileMode) error {
+	dst, err := bolt.Open(dstPath, mode, nil)
+	if err != nil {
+		return err
+	}
+
+	err = src.View(func(srcTx *bolt.Tx) error {
+		return dst.Update(func(dstTx *bolt.Tx) error {
+			return srcTx.ForEach(func(name []byte, srcBucket *bolt.Bucket) error {
+				dstBucket, err := dstTx.CreateBucketIfNotExists(name)
+				if err != nil {
+					return err
+				}
+				return copyBucket(dstBucket, srcBucket)
+			})
+		})
+	})
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// copyBucket copies all keys, nested buckets and sequences from src into dst.
+func copyBucket(dst, src *bolt.Bucket) error {
+	if err := dst.SetSequence(src.Sequence()); err != nil {
+		return err
+	}
+	return src.ForEach(func(k, v []byte) error {
+		if v == nil {
+			nestedSrc := src.Bucket(k)
+			nestedDst, err := dst.CreateBucketIfNotExists(k)
+			if err != nil {
+				return err
+			}
+			return copyBucket(nestedDst, nestedSrc)
+		}
+		return dst.Put(k, v)
+	})
+}
@@ -0,0 +1,246 @@
+package boltdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestCompactIfNeeded(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	compacted, err := db.CompactIfNeeded(0.5)
+	if err != nil || compacted {
+		t.Fatalf("CompactIfNeeded on fresh db = %v, %v; want false, nil", compacted, err)
+	}
+
+	// Fill and then mostly empty a bucket in two transactions, leaving free pages behind.
+	value := bytes.Repeat([]byte("x"), 1024)
+	err = db.db.Load().Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("b"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 2000; i++ {
+			if err := bucket.Put([]byte(fmt.Sprintf("k%04d", i)), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.db.Load().Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("b"))
+		for i := 100; i < 2000; i++ {
+			if err := bucket.Delete([]byte(fmt.Sprintf("k%04d", i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ratio, err := db.FragmentationRatio()
+	if err != nil || ratio <= 0.5 {
+		t.Fatalf("FragmentationRatio = %v, %v; want above 0.5", ratio, err)
+	}
+	before, err := os.Stat(db.dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compacted, err = db.CompactIfNeeded(0.5)
+	if err != nil || !compacted {
+		t.Fatalf("CompactIfNeeded = %v, %v; want true, nil", compacted, err)
+	}
+	after, err := os.Stat(db.dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() >= before.Size() {
+		t.Fatalf("file size %d after compaction, want below %d", after.Size(), before.Size())
+	}
+	all, err := db.List("b")
+	if err != nil || len(all) != 100 {
+		t.Fatalf("List = %d entries, %v; want 100", len(all), err)
+	}
+
+	compacted, err = db.CompactIfNeeded(0.5)
+	if err != nil || compacted {
+		t.Fatalf("CompactIfNeeded after compaction = %v, %v; want false, nil", compacted, err)
+	}
+}
+
+func TestRebuildWithPageSize(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "users", "alice", []byte("1"))
+	mustSet(t, db, "orders", "o1", []byte("2"))
+	dir := t.TempDir()
+
+	rebuilt, err := db.RebuildWithPageSize(filepath.Join(dir, "rebuilt.db"), os.Getpagesize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rebuilt.Close()
+	if equal, diffs, err := EqualContents(db, rebuilt); err != nil || !equal {
+		t.Fatalf("rebuilt copy differs: %v, %v", diffs, err)
+	}
+	if _, err := db.RebuildWithPageSize(filepath.Join(dir, "rebuilt.db"), os.Getpagesize()); err == nil {
+		t.Fatal("expected an error for an existing destination")
+	}
+
+	// Bolt v1.3.1 cannot create other page sizes, so a different size is rejected.
+	if _, err := db.RebuildWithPageSize(filepath.Join(dir, "big.db"), os.Getpagesize()*4); !errors.Is(err, ErrPageSizeUnsupported) {
+		t.Fatalf("other page size = %v, want ErrPageSizeUnsupported", err)
+	}
+	if _, err := db.RebuildWithPageSize(filepath.Join(dir, "odd.db"), 3000); err == nil || errors.Is(err, ErrPageSizeUnsupported) {
+		t.Fatalf("non power of two = %v, want a validation error", err)
+	}
+}
+
+func TestCompactInPlacePreservesSequences(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	if _, end, err := db.ReserveIDs("ids", 10); err != nil || end != 10 {
+		t.Fatalf("ReserveIDs = %d, %v; want end 10", end, err)
+	}
+	err := db.Update("ids", func(_ *bolt.Tx, bucket *bolt.Bucket) error {
+		nested, err := bucket.CreateBucket([]byte("nested"))
+		if err != nil {
+			return err
+		}
+		return nested.SetSequence(42)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.CompactInPlace(); err != nil {
+		t.Fatalf("CompactInPlace: %v", err)
+	}
+
+	start, end, err := db.ReserveIDs("ids", 10)
+	if err != nil || start != 11 || end != 20 {
+		t.Fatalf("ReserveIDs after compaction = %d-%d, %v; want 11-20", start, end, err)
+	}
+	err = db.View("ids", func(bucket *bolt.Bucket) error {
+		if seq := bucket.Bucket([]byte("nested")).Sequence(); seq != 42 {
+			return fmt.Errorf("nested sequence %d, want 42", seq)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompactInPlaceKeepsConcurrentWrites(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "b", "seed", []byte("v"))
+
+	const writers, perWriter = 4, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if err := db.Set("b", fmt.Sprintf("w%d-%d", w, i), []byte("v")); err != nil {
+					t.Errorf("Set: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		if err := db.CompactInPlace(); err != nil {
+			t.Fatalf("CompactInPlace: %v", err)
+		}
+	}
+	wg.Wait()
+
+	count, err := db.Count("b")
+	if err != nil || count != writers*perWriter+1 {
+		t.Fatalf("Count = %d, %v; want %d", count, err, writers*perWriter+1)
+	}
+}
+
+func TestCompactInPlaceRejectsReadOnly(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "b", "k", []byte("v"))
+	path := db.dbPath
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := NewBoltDatabaseWithOptions(path, 0600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	if err := ro.CompactInPlace(); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("CompactInPlace = %v, want ErrReadOnly", err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("read-only database file was rewritten")
+	}
+}
+
+func TestCompactInPlaceReopensOriginalWhenSwapFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := NewBoltDatabaseWithOptions(path, 0640, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	mustSet(t, db, "b", "k", []byte("v"))
+
+	failure := errors.New("rename failed")
+	renameFile = func(string, string) error { return failure }
+	defer func() { renameFile = os.Rename }()
+
+	if err := db.CompactInPlace(); !errors.Is(err, failure) {
+		t.Fatalf("CompactInPlace = %v, want the rename error", err)
+	}
+	if got, err := db.Get("b", "k"); err != nil || string(got) != "v" {
+		t.Fatalf("Get after failed swap = %q, %v", got, err)
+	}
+	mustSet(t, db, "b", "k2", []byte("v2"))
+	for _, leftover := range []string{path + ".compact", path + ".orig"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Fatalf("%s left behind: %v", leftover, err)
+		}
+	}
+
+	renameFile = os.Rename
+	if err := db.CompactInPlace(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0640 {
+		t.Fatalf("compacted file mode = %v, want 0640", perm)
+	}
+	if got, err := db.Get("b", "k2"); err != nil || string(got) != "v2" {
+		t.Fatalf("Get after compaction = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,189 @@
+package boltdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrInsufficientBalance is returned by Transfer when the source balance would go negative.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// ErrBalanceOverflow is returned by Transfer when a balance would overflow int64.
+var ErrBalanceOverflow = errors.New("balance overflow")
+
+// Increment atomically adds delta to a counter and returns the new value. The read, the
+// addition and the write happen in a single read-write transaction, so concurrent
+// increments never lose updates. Counters are stored as 8 bytes holding the int64 value
+// in big-endian two's complement, the encoding of binary.BigEndian.PutUint64(uint64(n)),
+// and a missing key counts as zero. The addition wraps on overflow like Go's int64.
+// If the bucket doesn't exist, it will be created automatically.
+//
+// Parameters:
+//   - bucketName: The name of the bucket holding the counter
+//   - key: The key of the counter
+//   - delta: The amount to add, which may be negative
+//
+// Returns:
+//   - int64: The new value of the counter
+//   - error: Any error that occurred, including a stored value that is not a counter
+func (b *BoltDatabase) Increment(bucketName, key string, delta int64) (int64, error) {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return 0, err
+	}
+	var value int64
+	err := b.update(func(tx *bolt.Tx) error {
+		bucket, err := b.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		current, err := b.readCounter(tx, bucket, bucketName, key)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", key, err)
+		}
+		value = current + delta
+		return b.putKey(tx, bucket, bucketName, key, encodeCounter(value))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// IncrementBounded atomically adds delta to a counter only if the result stays at or
+// below max, which makes it suitable for rate limiting. Counters use the same encoding
+// as Increment, and a missing key counts as zero. A delta that would overflow or
+// underflow int64 is not applied.
+//
+// Parameters:
+//   - bucketName: The name of the bucket holding the counter
+//   - key: The key of the counter
+//   - delta: The amount to add
+//   - max: The largest value the counter may reach
+//
+// Returns:
+//   - int64: The new value if applied, otherwise the unchanged value
+//   - bool: True if delta was applied
+//   - error: Any error that occurred, including a stored value that is not a counter
+func (b *BoltDatabase) IncrementBounded(bucketName, key string, delta, max int64) (int64, bool, error) {
+	if err := b.checkBucketName(bucketName); err != nil {
+		return 0, false, err
+	}
+	var value int64
+	applied := false
+	err := b.update(func(tx *bolt.Tx) error {
+		bucket, err := b.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		current, err := b.readCounter(tx, bucket, bucketName, key)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", key, err)
+		}
+		value = current
+		// A sum that wraps around int64 in either direction is rejected like one above max.
+		sum := current + delta
+		if (delta > 0 && sum < current) || (delta < 0 && sum > current) || sum > max {
+			return nil
+		}
+		value = sum
+		applied = true
+		return b.putKey(tx, bucket, bucketName, key, encodeCounter(value))
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return value, applied, nil
+}
+
+// Transfer atomically moves amount from one counter to another within a single read-write
+// transaction, as a minimal ledger. Balances use the same encoding as Increment, and a
+// missing key counts as zero. Unless Options.AllowNegativeBalance is set, a transfer that
+// would take the source below zero fails with ErrInsufficientBalance and changes nothing.
+// A transfer that would take either balance beyond the range of int64 fails with
+// ErrBalanceOverflow and changes nothing.
+// If the bucket doesn't exist, it will be created automatically.
+//
+// Parameters:
+//   - bucketName: The name of the bucket holding the balances
+//   - fromKey: The key of the balance to debit
+//   - toKey: The key of the balance to credit, different from fromKey
+//   - amount: The amount to move, not negative
+//
+// Returns:
+//   - int64: The new balance of fromKey
+//   - int64: The new balance of toKey
+//   - error: Any error that occurred, including ErrInsufficientBalance and ErrBalanceOverflow
+func (b *BoltDatabase) Transfer(bucketName, fromKey, toKey string, amount int64) (fromBal, toBal int64, err error) {
+	if amount < 0 {
+		return 0, 0, errors.New("amount must not be negative")
+	}
+	if fromKey == toKey {
+		return 0, 0, errors.New("cannot transfer to the same key")
+	}
+	if err := b.checkBucketName(bucketName); err != nil {
+		return 0, 0, err
+	}
+
+	err = b.update(func(tx *bolt.Tx) error {
+		bucket, err := b.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return err
+		}
+		from, err := b.readCounter(tx, bucket, bucketName, fromKey)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", fromKey, err)
+		}
+		to, err := b.readCounter(tx, bucket, bucketName, toKey)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", toKey, err)
+		}
+		if from < math.MinInt64+amount {
+			return fmt.Errorf("%w: debiting %d from %s", ErrBalanceOverflow, amount, fromKey)
+		}
+		if from-amount < 0 && !b.opts.AllowNegativeBalance {
+			return fmt.Errorf("%w: %s has %d, need %d", ErrInsufficientBalance, fromKey, from, amount)
+		}
+		if to > math.MaxInt64-amount {
+			return fmt.Errorf("%w: crediting %d to %s", ErrBalanceOverflow, amount, toKey)
+		}
+
+		fromBal, toBal = from-amount, to+amount
+		if err := b.putKey(tx, bucket, bucketName, fromKey, encodeCounter(fromBal)); err != nil {
+			return err
+		}
+		return b.putKey(tx, bucket, bucketName, toKey, encodeCounter(toBal))
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return fromBal, toBal, nil
+}
+
+// encodeCounter encodes a counter as an 8-byte big-endian value.
+func encodeCounter(n int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(n))
+}
+
+// readCounter reads the counter stored under key, treating a missing key as zero.
+func (b *BoltDatabase) readCounter(tx *bolt.Tx, bucket *bolt.Bucket, bucketName, key string) (int64, error) {
+	v, err := b.readValue(tx, bucketName, key, bucket.Get([]byte(key)))
+	if err != nil {
+		return 0, err
+	}
+	return decodeCounter(v)
+}
+
+// decodeCounter decodes a counter stored by encodeCounter, treating nil as zero.
+func decodeCounter(v []byte) (int64, error) {
+	if v == nil {
+		return 0, nil
+	}
+	if len(v) != 8 {
+		return 0, fmt.Errorf("counter value has %d bytes, want 8", len(v))
+	}
+	return int64(binary.BigEndian.Uint64(v)), nil
+}
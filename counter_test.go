@@ -0,0 +1,202 @@
+package boltdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIncrementBoundedNeverExceedsMax(t *testing.T) {
+	db := newTestDB(t, nil)
+	const workers, perWorker, max = 10, 10, 37
+
+	var applied atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				value, ok, err := db.IncrementBounded("limits", "hits", 1, max)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if value > max {
+					t.Errorf("counter reached %d, above max %d", value, max)
+				}
+				if ok {
+					applied.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if applied.Load() != max {
+		t.Fatalf("%d increments applied, want %d", applied.Load(), max)
+	}
+	value, ok, err := db.IncrementBounded("limits", "hits", 0, max)
+	if err != nil || !ok || value != max {
+		t.Fatalf("final counter = %d, %v, %v; want %d", value, ok, err, max)
+	}
+}
+
+func TestIncrementConcurrentUpdatesAreNotLost(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if _, err := db.Increment("views", "home", 2); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err := db.Increment("views", "home", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 199 {
+		t.Fatalf("counter = %d, want 199", value)
+	}
+	raw, err := db.Get("views", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := int64(binary.BigEndian.Uint64(raw)); len(raw) != 8 || n != 199 {
+		t.Fatalf("stored %x, want 8-byte big-endian 199", raw)
+	}
+}
+
+func TestTransferConservesTotalUnderConcurrency(t *testing.T) {
+	db := newTestDB(t, nil)
+	const accounts, initial = 5, 100
+	for i := 0; i < accounts; i++ {
+		if _, err := db.Increment("ledger", strconv.Itoa(i), initial); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				from, to := strconv.Itoa((w+i)%accounts), strconv.Itoa((w+2*i+1)%accounts)
+				if from == to {
+					continue
+				}
+				_, _, err := db.Transfer("ledger", from, to, int64(i%7))
+				if err != nil && !errors.Is(err, ErrInsufficientBalance) {
+					t.Error(err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var total int64
+	for i := 0; i < accounts; i++ {
+		balance, err := db.Increment("ledger", strconv.Itoa(i), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if balance < 0 {
+			t.Errorf("balance %d is %d, below zero", i, balance)
+		}
+		total += balance
+	}
+	if total != accounts*initial {
+		t.Fatalf("total = %d, want %d", total, accounts*initial)
+	}
+}
+
+func TestTransferRejectsOverdraftUnlessAllowed(t *testing.T) {
+	db := newTestDB(t, nil)
+	if _, _, err := db.Transfer("ledger", "a", "b", 1); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("overdraft error = %v, want ErrInsufficientBalance", err)
+	}
+
+	db = newTestDB(t, &Options{AllowNegativeBalance: true})
+	from, to, err := db.Transfer("ledger", "a", "b", 3)
+	if err != nil || from != -3 || to != 3 {
+		t.Fatalf("Transfer = %d, %d, %v; want -3, 3", from, to, err)
+	}
+}
+
+func TestTransferRejectsOverflow(t *testing.T) {
+	db := newTestDB(t, &Options{AllowNegativeBalance: true})
+	seed := func(key string, n int64) {
+		t.Helper()
+		if err := db.Set("ledger", key, encodeCounter(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed("rich", math.MaxInt64)
+	seed("poor", math.MinInt64+1)
+	seed("payer", 10)
+
+	if _, _, err := db.Transfer("ledger", "payer", "rich", 1); !errors.Is(err, ErrBalanceOverflow) {
+		t.Fatalf("credit overflow error = %v, want ErrBalanceOverflow", err)
+	}
+	if _, _, err := db.Transfer("ledger", "poor", "payer", 2); !errors.Is(err, ErrBalanceOverflow) {
+		t.Fatalf("debit overflow error = %v, want ErrBalanceOverflow", err)
+	}
+
+	for key, want := range map[string]int64{"rich": math.MaxInt64, "poor": math.MinInt64 + 1, "payer": 10} {
+		if got, err := db.Increment("ledger", key, 0); err != nil || got != want {
+			t.Fatalf("%s = %d, %v; want %d unchanged", key, got, err, want)
+		}
+	}
+}
+
+func TestIncrementBounded(t *testing.T) {
+	tests := []struct {
+		name        string
+		start       int64
+		delta, max  int64
+		wantValue   int64
+		wantApplied bool
+	}{
+		{"within bound", 5, 3, 10, 8, true},
+		{"reaches bound", 5, 5, 10, 10, true},
+		{"above bound", 5, 6, 10, 5, false},
+		{"negative delta", 5, -7, 10, -2, true},
+		{"overflow", math.MaxInt64 - 1, 2, math.MaxInt64, math.MaxInt64 - 1, false},
+		{"overflow with max delta", 1, math.MaxInt64, math.MaxInt64, 1, false},
+		{"underflow", math.MinInt64 + 1, -2, math.MaxInt64, math.MinInt64 + 1, false},
+		{"large negative start", math.MinInt64, math.MaxInt64, 0, -1, true},
+	}
+
+	db := newTestDB(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.Set("counters", tt.name, encodeCounter(tt.start)); err != nil {
+				t.Fatal(err)
+			}
+			value, applied, err := db.IncrementBounded("counters", tt.name, tt.delta, tt.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != tt.wantValue || applied != tt.wantApplied {
+				t.Fatalf("IncrementBounded = %d, %v; want %d, %v", value, applied, tt.wantValue, tt.wantApplied)
+			}
+			stored, err := db.Increment("counters", tt.name, 0)
+			if err != nil || stored != tt.wantValue {
+				t.Fatalf("stored counter = %d, %v; want %d", stored, err, tt.wantValue)
+			}
+		})
+	}
+}
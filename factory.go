@@ -1,17 +1,33 @@
 package boltdb
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"sync"
+
+	"github.com/boltdb/bolt"
 )
 
+// DatabaseStatus describes a database managed by the factory.
+type DatabaseStatus struct {
+	Name        string // The name the database is registered under
+	Path        string // The file path of the database
+	Open        bool   // Whether the database is open
+	SizeBytes   int64  // The size of the database file
+	BucketCount int    // The number of top-level buckets
+}
+
 // BoltFactory manages multiple Bolt database instances with thread-safe operations.
 // It provides a centralized way to create, access, and manage multiple databases
 // with different names and file paths. All operations are protected by read-write locks
 // to ensure thread safety in concurrent environments.
 type BoltFactory struct {
-	lck       sync.RWMutex             // Read-write lock for thread-safe operations
-	databases map[string]*BoltDatabase // Map of database names to database instances
+	lck       sync.RWMutex                // Read-write lock for thread-safe operations
+	databases map[string]*BoltDatabase    // Map of database names to database instances
+	codecs    map[string]map[string]Codec // Database name -> bucket name -> codec
 }
 
 // NewBoltFactory creates a new factory instance with an initial database.
@@ -25,13 +41,11 @@ type BoltFactory struct {
 //   - *BoltFactory: A new factory instance
 //   - error: An error if the initial database cannot be created
 func NewBoltFactory(name, defaultPath string) (*BoltFactory, error) {
-	databases := make(map[string]*BoltDatabase)
-	databases[name] = NewBoltDatabase(defaultPath)
-
-	if err := databases[name]; err != nil {
-		return nil, fmt.Errorf("could not open database %s: %v", name, err)
+	db, err := NewBoltDatabase(defaultPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open database %s: %w", name, err)
 	}
-	return &BoltFactory{databases: databases}, nil
+	return &BoltFactory{databases: map[string]*BoltDatabase{name: db}}, nil
 }
 
 // GetDatabases returns a list of all database names currently managed by the factory.
@@ -53,6 +67,7 @@ func (f *BoltFactory) GetDatabases() ([]string, error) {
 
 // Open creates a new database instance and adds it to the factory's management.
 // If a database with the same name already exists, it will be replaced.
+// If the database cannot be opened, nothing is registered under the name.
 // This operation is thread-safe and uses a write lock.
 //
 // Parameters:
@@ -65,8 +80,39 @@ func (f *BoltFactory) GetDatabases() ([]string, error) {
 func (f *BoltFactory) Open(name, path string) (*BoltDatabase, error) {
 	f.lck.Lock()
 	defer f.lck.Unlock()
-	f.databases[name] = NewBoltDatabase(path)
-	return f.databases[name], nil
+	return f.openLocked(name, path, nil)
+}
+
+// OpenReadOnly opens a database in read-only mode and adds it to the factory's management.
+// The file is opened by bolt with ReadOnly set, so it takes a shared lock and several
+// read-only processes can open it at once. Writes through the returned instance fail
+// with ErrReadOnly. If a database with the same name already exists, it will be replaced.
+// This operation is thread-safe and uses a write lock.
+//
+// Parameters:
+//   - name: The name identifier for the database
+//   - path: The file path of an existing database
+//
+// Returns:
+//   - *BoltDatabase: The newly opened database instance
+//   - error: Any error that occurred while opening the database
+func (f *BoltFactory) OpenReadOnly(name, path string) (*BoltDatabase, error) {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+	return f.openLocked(name, path, &bolt.Options{ReadOnly: true})
+}
+
+// openLocked opens a database and registers it under name. The caller must hold the write lock.
+func (f *BoltFactory) openLocked(name, path string, opts *bolt.Options) (*BoltDatabase, error) {
+	db, err := NewBoltDatabaseWithOptions(path, 0600, opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not open database %s: %w", name, err)
+	}
+	for bucketName, codec := range f.codecs[name] {
+		db.SetBucketCodec(bucketName, codec)
+	}
+	f.databases[name] = db
+	return db, nil
 }
 
 // Close closes a specific database and removes it from the factory's management.
@@ -86,8 +132,10 @@ func (f *BoltFactory) Close(name string) error {
 		return fmt.Errorf("database %s not found", name)
 	}
 
-	if err := db.Close(); err != nil {
-		return err
+	if db != nil {
+		if err := db.Close(); err != nil {
+			return err
+		}
 	}
 
 	delete(f.databases, name)
@@ -128,6 +176,198 @@ func (f *BoltFactory) Get(name string) (*BoltDatabase, error) {
 	if !ok {
 		return nil, fmt.Errorf("database %s not found", name)
 	}
+	if db == nil {
+		return nil, fmt.Errorf("database %s is not open", name)
+	}
 
 	return db, nil
 }
+
+// RegisterBucketCodec records the codec for a bucket of a named database, so wrappers
+// obtained through BoltDatabase.Bucket are configured with it. The registration applies
+// to the database if it is already open and to any database later opened under the name.
+// This operation is thread-safe and uses a write lock.
+//
+// Parameters:
+//   - dbName: The name of the database
+//   - bucketName: The name of the bucket
+//   - codec: The codec for the bucket's values
+func (f *BoltFactory) RegisterBucketCodec(dbName, bucketName string, codec Codec) {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+
+	if f.codecs == nil {
+		f.codecs = make(map[string]map[string]Codec)
+	}
+	if f.codecs[dbName] == nil {
+		f.codecs[dbName] = make(map[string]Codec)
+	}
+	f.codecs[dbName][bucketName] = codec
+
+	if db := f.databases[dbName]; db != nil {
+		db.SetBucketCodec(bucketName, codec)
+	}
+}
+
+// List returns the status of every database managed by the factory, sorted by name.
+// Entries whose database failed to open report Open as false with zero sizes.
+// This operation is thread-safe and uses a read lock.
+//
+// Returns:
+//   - []DatabaseStatus: The status of each database
+//   - error: Any error that occurred while inspecting an open database
+func (f *BoltFactory) List() ([]DatabaseStatus, error) {
+	f.lck.RLock()
+	defer f.lck.RUnlock()
+
+	statuses := make([]DatabaseStatus, 0, len(f.databases))
+	for name, db := range f.databases {
+		status := DatabaseStatus{Name: name}
+		if db != nil {
+			status.Path = db.dbPath
+			status.Open = true
+
+			info, err := os.Stat(db.dbPath)
+			if err != nil {
+				return nil, err
+			}
+			status.SizeBytes = info.Size()
+
+			err = db.db.Load().View(func(tx *bolt.Tx) error {
+				return tx.ForEach(func(_ []byte, _ *bolt.Bucket) error {
+					status.BucketCount++
+					return nil
+				})
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses, nil
+}
+
+// Reconcile brings the set of managed databases in line with a desired configuration.
+// Databases missing from desired are closed, new names are opened, and databases whose
+// path changed are closed and reopened at the new path. Unchanged databases are left
+// untouched. Failures are collected and reconciliation continues with the other names.
+// This operation is thread-safe and uses a write lock.
+//
+// Parameters:
+//   - desired: A map of database names to file paths
+//
+// Returns:
+//   - []string: The names of the databases opened, sorted
+//   - []string: The names of the databases closed, sorted
+//   - error: The errors of every failed open or close, joined together
+func (f *BoltFactory) Reconcile(desired map[string]string) (opened, closed []string, err error) {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+
+	var errs []error
+	for name, db := range f.databases {
+		path, ok := desired[name]
+		if ok && db != nil && db.dbPath == path {
+			continue
+		}
+		if db != nil {
+			if err := db.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close database %s: %w", name, err))
+				continue
+			}
+		}
+		delete(f.databases, name)
+		closed = append(closed, name)
+	}
+
+	for name, path := range desired {
+		if _, ok := f.databases[name]; ok {
+			continue
+		}
+		if _, err := f.openLocked(name, path, nil); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		opened = append(opened, name)
+	}
+
+	sort.Strings(opened)
+	sort.Strings(closed)
+	return opened, closed, errors.Join(errs...)
+}
+
+// DumpConfig serializes the databases managed by the factory as a JSON object mapping
+// each name to its file path, for use with LoadFactoryConfig or Reconcile.
+// Only the name and path are recorded, so databases opened read-only are restored as
+// read-write. This operation is thread-safe and uses a read lock.
+//
+// Returns:
+//   - []byte: The JSON configuration
+//   - error: Any error that occurred while encoding
+func (f *BoltFactory) DumpConfig() ([]byte, error) {
+	f.lck.RLock()
+	defer f.lck.RUnlock()
+
+	config := make(map[string]string, len(f.databases))
+	for name, db := range f.databases {
+		if db != nil {
+			config[name] = db.dbPath
+		}
+	}
+	return json.Marshal(config)
+}
+
+// LoadFactoryConfig builds a factory from a configuration produced by DumpConfig,
+// opening every database it lists. Databases that fail to open are reported
+// individually and left out of the factory, while the others are still opened.
+//
+// Parameters:
+//   - data: The JSON configuration mapping database names to file paths
+//
+// Returns:
+//   - *BoltFactory: A new factory managing the databases that opened
+//   - map[string]error: The open error of each database that failed, keyed by name
+//   - error: An error if the configuration cannot be decoded
+func LoadFactoryConfig(data []byte) (*BoltFactory, map[string]error, error) {
+	var config map[string]string
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, nil, fmt.Errorf("could not decode factory config: %w", err)
+	}
+
+	f := &BoltFactory{databases: make(map[string]*BoltDatabase, len(config))}
+	openErrs := make(map[string]error)
+	for name, path := range config {
+		if _, err := f.openLocked(name, path, nil); err != nil {
+			openErrs[name] = err
+		}
+	}
+	return f, openErrs, nil
+}
+
+// ClearAll deletes all data from every database managed by the factory by calling
+// DropAll on each. The databases stay open and registered, just empty. A failure on one
+// database does not stop the others from being cleared.
+// This operation is thread-safe and uses a write lock.
+//
+// Returns:
+//   - map[string]error: The error of each database that could not be cleared, keyed by name
+func (f *BoltFactory) ClearAll() map[string]error {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+
+	errs := make(map[string]error)
+	for name, db := range f.databases {
+		if db == nil {
+			continue
+		}
+		if err := db.DropAll(); err != nil {
+			errs[name] = err
+		}
+	}
+	return errs
+}
@@ -0,0 +1,233 @@
+package boltdb
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// closeOnCleanup closes every database still managed by f when the test ends.
+func closeOnCleanup(t *testing.T, f *BoltFactory) {
+	t.Cleanup(func() {
+		names, _ := f.GetDatabases()
+		for _, name := range names {
+			f.Close(name)
+		}
+	})
+}
+
+func TestFactoryOpenRejectsInvalidPath(t *testing.T) {
+	f := newTestFactory(t)
+
+	db, err := f.Open("bad", filepath.Join(t.TempDir(), "missing", "test.db"))
+	if err == nil || db != nil {
+		t.Fatalf("Open = %v, %v; want nil and an error", db, err)
+	}
+	if got, err := f.Get("bad"); err == nil || got != nil {
+		t.Fatalf("Get = %v, %v; want nil and an error", got, err)
+	}
+	if names, _ := f.GetDatabases(); len(names) != 0 {
+		t.Fatalf("failed open registered %v", names)
+	}
+
+	db, err = f.Open("good", filepath.Join(t.TempDir(), "good.db"))
+	if err != nil || db == nil {
+		t.Fatalf("Open valid path = %v, %v", db, err)
+	}
+	defer db.Close()
+	if got, err := f.Get("good"); err != nil || got != db {
+		t.Fatalf("Get = %v, %v; want the opened database", got, err)
+	}
+}
+
+func TestFactoryListReportsSortedStatuses(t *testing.T) {
+	f := newTestFactory(t, "zeta", "alpha")
+	f.databases["broken"] = nil // As left by a failed open before opens were rejected.
+	alpha, _ := f.Get("alpha")
+	mustSet(t, alpha, "a", "k", []byte("v"))
+	mustSet(t, alpha, "b", "k", []byte("v"))
+
+	statuses, err := f.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("got %d statuses, want 3", len(statuses))
+	}
+	for i, name := range []string{"alpha", "broken", "zeta"} {
+		if statuses[i].Name != name {
+			t.Fatalf("status %d is %q, want %q", i, statuses[i].Name, name)
+		}
+	}
+
+	if s := statuses[0]; !s.Open || s.Path != alpha.dbPath || s.SizeBytes == 0 || s.BucketCount != 2 {
+		t.Fatalf("alpha: %+v", s)
+	}
+	if s := statuses[1]; s.Open || s.Path != "" || s.SizeBytes != 0 || s.BucketCount != 0 {
+		t.Fatalf("broken: %+v", s)
+	}
+	if s := statuses[2]; !s.Open || s.BucketCount != 0 {
+		t.Fatalf("zeta: %+v", s)
+	}
+}
+
+func TestReconcileAppliesConfigurationDiff(t *testing.T) {
+	dir := t.TempDir()
+	f := newTestFactory(t)
+	closeOnCleanup(t, f)
+	for _, name := range []string{"keep", "drop", "move"} {
+		if _, err := f.Open(name, filepath.Join(dir, name+".db")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kept, _ := f.Get("keep")
+
+	opened, closed, err := f.Reconcile(map[string]string{
+		"keep": filepath.Join(dir, "keep.db"),
+		"move": filepath.Join(dir, "moved.db"),
+		"add":  filepath.Join(dir, "add.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(opened, ","); got != "add,move" {
+		t.Fatalf("opened %q, want add,move", got)
+	}
+	if got := strings.Join(closed, ","); got != "drop,move" {
+		t.Fatalf("closed %q, want drop,move", got)
+	}
+
+	if db, _ := f.Get("keep"); db != kept {
+		t.Fatal("unchanged database was reopened")
+	}
+	if db, _ := f.Get("move"); db == nil || db.dbPath != filepath.Join(dir, "moved.db") {
+		t.Fatalf("moved database not reopened at the new path: %v", db)
+	}
+	if _, err := f.Get("drop"); err == nil {
+		t.Fatal("removed database is still registered")
+	}
+}
+
+func TestNewBoltFactorySurfacesOpenError(t *testing.T) {
+	f, err := NewBoltFactory("main", filepath.Join(t.TempDir(), "missing", "test.db"))
+	if err == nil || f != nil {
+		t.Fatalf("NewBoltFactory = %v, %v; want nil and an error", f, err)
+	}
+
+	f, err = NewBoltFactory("main", filepath.Join(t.TempDir(), "main.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close("main")
+	if db, err := f.Get("main"); err != nil || db == nil {
+		t.Fatalf("Get = %v, %v", db, err)
+	}
+}
+
+func TestOpenReadOnlyRejectsWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ro.db")
+	db, err := NewBoltDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, db, "data", "k", []byte("v"))
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newTestFactory(t)
+	ro, err := f.OpenReadOnly("ro", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close("ro")
+
+	if err := ro.Set("data", "k", []byte("changed")); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Set = %v, want ErrReadOnly", err)
+	}
+	if err := ro.Delete("data", "k"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Delete = %v, want ErrReadOnly", err)
+	}
+	if v, err := ro.Get("data", "k"); err != nil || string(v) != "v" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+}
+
+func TestDumpConfigLoadFactoryConfigRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	f := newTestFactory(t)
+	for _, name := range []string{"users", "orders"} {
+		if _, err := f.Open(name, filepath.Join(dir, name+".db")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := f.DumpConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"users", "orders"} {
+		if err := f.Close(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, openErrs, err := LoadFactoryConfig(data)
+	if err != nil || len(openErrs) != 0 {
+		t.Fatalf("LoadFactoryConfig: %v, %v", openErrs, err)
+	}
+	closeOnCleanup(t, loaded)
+	names, _ := loaded.GetDatabases()
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "orders,users" {
+		t.Fatalf("loaded databases %q, want orders,users", got)
+	}
+	if db, _ := loaded.Get("users"); db.dbPath != filepath.Join(dir, "users.db") {
+		t.Fatalf("users opened at %s", db.dbPath)
+	}
+}
+
+func TestLoadFactoryConfigReportsOpenErrors(t *testing.T) {
+	dir := t.TempDir()
+	data := fmt.Sprintf(`{"good": %q, "bad": %q}`, filepath.Join(dir, "good.db"), filepath.Join(dir, "missing", "bad.db"))
+
+	f, openErrs, err := LoadFactoryConfig([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeOnCleanup(t, f)
+	if len(openErrs) != 1 || openErrs["bad"] == nil {
+		t.Fatalf("open errors = %v, want one for bad", openErrs)
+	}
+	if names, _ := f.GetDatabases(); len(names) != 1 || names[0] != "good" {
+		t.Fatalf("loaded databases %v, want [good]", names)
+	}
+
+	if _, _, err := LoadFactoryConfig([]byte("not json")); err == nil {
+		t.Fatal("expected an error for an invalid configuration")
+	}
+}
+
+func TestClearAllEmptiesDatabasesAndKeepsThemUsable(t *testing.T) {
+	f := newTestFactory(t, "users", "orders")
+	users, _ := f.Get("users")
+	orders, _ := f.Get("orders")
+	mustSet(t, users, "active", "alice", []byte("1"))
+	mustSet(t, users, "archived", "bob", []byte("2"))
+	mustSet(t, orders, "open", "o1", []byte("3"))
+
+	if errs := f.ClearAll(); len(errs) != 0 {
+		t.Fatalf("ClearAll errors: %v", errs)
+	}
+	for name, db := range map[string]*BoltDatabase{"users": users, "orders": orders} {
+		if buckets := bucketNames(t, db); len(buckets) != 0 {
+			t.Fatalf("%s still has buckets %v", name, buckets)
+		}
+		mustSet(t, db, "fresh", "k", []byte("v"))
+		if v, err := db.Get("fresh", "k"); err != nil || string(v) != "v" {
+			t.Fatalf("%s unusable after ClearAll: %q, %v", name, v, err)
+		}
+	}
+}
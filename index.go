@@ -0,0 +1,202 @@
+package boltdb
+
+import (
+	"slices"
+
+	"github.com/boltdb/bolt"
+)
+
+// secondaryIndex describes an index maintained over a data bucket.
+type secondaryIndex struct {
+	indexBucket string
+	extract     func(key string, value []byte) string
+}
+
+// CreateIndex builds a secondary index over a data bucket and keeps it up to date on
+// later writes through Set, Delete, batches and the other write methods.
+// The index bucket maps each index key to the primary keys having it, so several
+// records may share an index key. Records for which extract returns an empty string
+// are not indexed. Any existing contents of the index bucket are replaced.
+// Index registrations live in memory, so CreateIndex must be called again after the
+// database is reopened. Writes made directly through the bolt bucket given by Update do
+// not maintain the index.
+//
+// Parameters:
+//   - dataBucket: The name of the bucket holding the records
+//   - indexBucket: The name of the bucket holding the index
+//   - extract: A function returning the index key of a record
+//
+// Returns:
+//   - error: Any error that occurred while building the index
+func (b *BoltDatabase) CreateIndex(dataBucket, indexBucket string, extract func(key string, value []byte) (indexKey string)) error {
+	if err := b.checkBucketName(indexBucket); err != nil {
+		return err
+	}
+
+	idx := secondaryIndex{indexBucket: indexBucket, extract: extract}
+
+	// Register before rebuilding: writes committed before the rebuild transaction are
+	// picked up by its scan, and writes after it maintain the index themselves.
+	previous, replaced := b.registerIndex(dataBucket, idx)
+
+	err := b.update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(indexBucket)) != nil {
+			if err := tx.DeleteBucket([]byte(indexBucket)); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.CreateBucket([]byte(indexBucket)); err != nil {
+			return err
+		}
+
+		data := tx.Bucket([]byte(dataBucket))
+		if data == nil {
+			return nil
+		}
+		return data.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return nil
+			}
+			value, err := b.entryValue(tx, dataBucket, k, v)
+			if err != nil {
+				return err
+			}
+			return idx.add(tx, string(k), value)
+		})
+	})
+	if err != nil {
+		if replaced {
+			b.registerIndex(dataBucket, previous)
+		} else {
+			b.unregisterIndex(dataBucket, indexBucket)
+		}
+		return err
+	}
+	return nil
+}
+
+// registerIndex adds or replaces the index over dataBucket with the same index bucket,
+// returning the index it replaced.
+func (b *BoltDatabase) registerIndex(dataBucket string, idx secondaryIndex) (secondaryIndex, bool) {
+	b.indexLck.Lock()
+	defer b.indexLck.Unlock()
+
+	if b.indexes == nil {
+		b.indexes = make(map[string][]secondaryIndex)
+	}
+	// Copy on write, since updateIndexes reads the slice without holding the lock.
+	existing := slices.Clone(b.indexes[dataBucket])
+	for i := range existing {
+		if existing[i].indexBucket == idx.indexBucket {
+			previous := existing[i]
+			existing[i] = idx
+			b.indexes[dataBucket] = existing
+			return previous, true
+		}
+	}
+	b.indexes[dataBucket] = append(existing, idx)
+	return secondaryIndex{}, false
+}
+
+// unregisterIndex removes the index over dataBucket stored in indexBucket.
+func (b *BoltDatabase) unregisterIndex(dataBucket, indexBucket string) {
+	b.indexLck.Lock()
+	defer b.indexLck.Unlock()
+
+	b.indexes[dataBucket] = slices.DeleteFunc(slices.Clone(b.indexes[dataBucket]), func(idx secondaryIndex) bool {
+		return idx.indexBucket == indexBucket
+	})
+}
+
+// QueryByIndex returns the primary keys of all records with the given index key.
+//
+// Parameters:
+//   - indexBucket: The name of the bucket holding the index
+//   - indexKey: The index key to look up
+//
+// Returns:
+//   - []string: The matching primary keys in sorted order
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) QueryByIndex(indexBucket, indexKey string) ([]string, error) {
+	result := make([]string, 0)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		index := tx.Bucket([]byte(indexBucket))
+		if index == nil {
+			return nil
+		}
+		entries := index.Bucket([]byte(indexKey))
+		if entries == nil {
+			return nil
+		}
+		return entries.ForEach(func(k, _ []byte) error {
+			result = append(result, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// updateIndexes moves a record's entries in every index over bucketName from the index
+// key of oldValue to that of newValue. A nil value means the record is absent.
+func (b *BoltDatabase) updateIndexes(tx *bolt.Tx, bucketName, key string, oldValue, newValue []byte) error {
+	b.indexLck.RLock()
+	indexes := b.indexes[bucketName]
+	b.indexLck.RUnlock()
+
+	for _, idx := range indexes {
+		if oldValue != nil {
+			if err := idx.remove(tx, key, oldValue); err != nil {
+				return err
+			}
+		}
+		if newValue != nil {
+			if err := idx.add(tx, key, newValue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// add records key under the index key of value.
+func (idx secondaryIndex) add(tx *bolt.Tx, key string, value []byte) error {
+	indexKey := idx.extract(key, value)
+	if indexKey == "" {
+		return nil
+	}
+	index, err := tx.CreateBucketIfNotExists([]byte(idx.indexBucket))
+	if err != nil {
+		return err
+	}
+	entries, err := index.CreateBucketIfNotExists([]byte(indexKey))
+	if err != nil {
+		return err
+	}
+	return entries.Put([]byte(key), []byte{})
+}
+
+// remove drops key from the index key of value, deleting the entry set once empty.
+func (idx secondaryIndex) remove(tx *bolt.Tx, key string, value []byte) error {
+	indexKey := idx.extract(key, value)
+	if indexKey == "" {
+		return nil
+	}
+	index := tx.Bucket([]byte(idx.indexBucket))
+	if index == nil {
+		return nil
+	}
+	entries := index.Bucket([]byte(indexKey))
+	if entries == nil {
+		return nil
+	}
+	if err := entries.Delete([]byte(key)); err != nil {
+		return err
+	}
+	if k, _ := entries.Cursor().First(); k == nil {
+		return index.DeleteBucket([]byte(indexKey))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package boltdb
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// cityOf indexes "name|city" records by city.
+func cityOf(_ string, value []byte) string {
+	_, city, _ := strings.Cut(string(value), "|")
+	return city
+}
+
+func TestIndexMaintainedOnSetAndDelete(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "users", "u1", []byte("alice|paris"))
+	mustSet(t, db, "users", "u2", []byte("bob|rome"))
+
+	if err := db.CreateIndex("users", "users_by_city", cityOf); err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, db, "users", "u3", []byte("carol|paris"))
+	mustSet(t, db, "users", "u2", []byte("bob|paris"))
+	if err := db.Delete("users", "u1"); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(city string) string {
+		t.Helper()
+		keys, err := db.QueryByIndex("users_by_city", city)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(keys, ",")
+	}
+	if got := query("paris"); got != "u2,u3" {
+		t.Fatalf("paris = %q, want u2,u3", got)
+	}
+	if got := query("rome"); got != "" {
+		t.Fatalf("rome = %q, want no keys", got)
+	}
+}
+
+// colorIndex indexes records by their value.
+func colorIndex(_ string, value []byte) string {
+	return string(value)
+}
+
+func TestIndexMaintainedByEveryWritePath(t *testing.T) {
+	writes := map[string]func(db *BoltDatabase) error{
+		"Set": func(db *BoltDatabase) error {
+			return db.Set("items", "k", []byte("blue"))
+		},
+		"Batch": func(db *BoltDatabase) error {
+			batch := db.NewBatch()
+			if err := batch.AddSet("items", "k", []byte("blue")); err != nil {
+				return err
+			}
+			return batch.Execute()
+		},
+		"ExecuteAtomic": func(db *BoltDatabase) error {
+			batch := db.NewBatch()
+			if err := batch.AddSet("items", "k", []byte("blue")); err != nil {
+				return err
+			}
+			return batch.ExecuteAtomic()
+		},
+		"Modify": func(db *BoltDatabase) error {
+			return db.Modify("items", "k", func([]byte) ([]byte, error) { return []byte("blue"), nil })
+		},
+		"SetIfChanged": func(db *BoltDatabase) error {
+			_, err := db.SetIfChanged("items", "k", []byte("blue"))
+			return err
+		},
+		"SetWithCommitHook": func(db *BoltDatabase) error {
+			return db.SetWithCommitHook("items", "k", []byte("blue"), func() {})
+		},
+		"StagedWrapper": func(db *BoltDatabase) error {
+			staged := NewStagedWrapper(db, "items")
+			staged.Set("k", []byte("blue"))
+			return staged.Flush()
+		},
+		"ImportBucketFrom": func(db *BoltDatabase) error {
+			if err := db.Set("incoming", "k", []byte("blue")); err != nil {
+				return err
+			}
+			return db.ImportBucketFrom(db, "incoming", "items", nil)
+		},
+	}
+
+	for name, write := range writes {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDB(t, nil)
+			mustSet(t, db, "items", "k", []byte("red"))
+			if err := db.CreateIndex("items", "by-color", colorIndex); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := write(db); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if keys, err := db.QueryByIndex("by-color", "red"); err != nil || len(keys) != 0 {
+				t.Fatalf("QueryByIndex(red) = %v, %v; want no keys", keys, err)
+			}
+			if keys, err := db.QueryByIndex("by-color", "blue"); err != nil || !slices.Equal(keys, []string{"k"}) {
+				t.Fatalf("QueryByIndex(blue) = %v, %v; want [k]", keys, err)
+			}
+		})
+	}
+}
+
+func TestIndexMaintainedByDeletePaths(t *testing.T) {
+	deletes := map[string]func(db *BoltDatabase) error{
+		"Delete": func(db *BoltDatabase) error {
+			return db.Delete("items", "k")
+		},
+		"Batch": func(db *BoltDatabase) error {
+			batch := db.NewBatch()
+			if err := batch.AddDelete("items", "k"); err != nil {
+				return err
+			}
+			return batch.Execute()
+		},
+		"Modify": func(db *BoltDatabase) error {
+			return db.Modify("items", "k", func([]byte) ([]byte, error) { return nil, nil })
+		},
+		"StagedWrapper": func(db *BoltDatabase) error {
+			staged := NewStagedWrapper(db, "items")
+			staged.Delete("k")
+			return staged.Flush()
+		},
+		"DeleteWhere": func(db *BoltDatabase) error {
+			_, err := db.DeleteWhere("items", func(_, _ []byte) bool { return true })
+			return err
+		},
+	}
+
+	for name, del := range deletes {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDB(t, nil)
+			mustSet(t, db, "items", "k", []byte("red"))
+			if err := db.CreateIndex("items", "by-color", colorIndex); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := del(db); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			for _, color := range []string{"red", "blue"} {
+				if keys, err := db.QueryByIndex("by-color", color); err != nil || len(keys) != 0 {
+					t.Fatalf("QueryByIndex(%s) = %v, %v; want no keys", color, keys, err)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexRestoredByCheckpoint(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "items", "k", []byte("red"))
+	if err := db.CreateIndex("items", "by-color", colorIndex); err != nil {
+		t.Fatal(err)
+	}
+	restore, err := db.Checkpoint("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, db, "items", "k", []byte("blue"))
+	mustSet(t, db, "items", "k2", []byte("blue"))
+
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := db.QueryByIndex("by-color", "red"); err != nil || !slices.Equal(keys, []string{"k"}) {
+		t.Fatalf("QueryByIndex(red) = %v, %v; want [k]", keys, err)
+	}
+	if keys, err := db.QueryByIndex("by-color", "blue"); err != nil || len(keys) != 0 {
+		t.Fatalf("QueryByIndex(blue) = %v, %v; want no keys", keys, err)
+	}
+}
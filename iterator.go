@@ -0,0 +1,100 @@
+package boltdb
+
+import (
+	"github.com/boltdb/bolt"
+)
+
+// Iterator walks the entries of a bucket in key order without loading them all.
+// It holds a read transaction open from creation until Close, so it sees a consistent
+// snapshot; long-lived iterators keep old pages from being reused and can delay
+// database growth. Always call Close when done.
+//
+// The slice returned by Value points into the database's memory map, or holds a
+// reassembled chunked value, and is only valid until the next call to Next or Close.
+// Copy it to keep it longer.
+type Iterator struct {
+	db         *BoltDatabase
+	bucketName string
+	tx         *bolt.Tx
+	cursor     *bolt.Cursor // nil when the bucket doesn't exist
+	started    bool
+	key        []byte
+	value      []byte
+	err        error
+}
+
+// Iterator returns a cursor-backed iterator over the specified bucket.
+// If the bucket doesn't exist, the iterator yields no entries.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to iterate over
+//
+// Returns:
+//   - *Iterator: A new iterator positioned before the first entry
+//   - error: An error if the read transaction cannot be started
+func (b *BoltDatabase) Iterator(bucketName string) (*Iterator, error) {
+	tx, err := b.db.Load().Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	it := &Iterator{db: b, bucketName: bucketName, tx: tx}
+	if bucket := tx.Bucket([]byte(bucketName)); bucket != nil {
+		it.cursor = bucket.Cursor()
+	}
+	return it, nil
+}
+
+// Next advances to the next entry.
+//
+// Returns:
+//   - bool: True if an entry is available, false when exhausted, closed or failed
+func (it *Iterator) Next() bool {
+	if it.tx == nil || it.cursor == nil || it.err != nil {
+		return false
+	}
+	var stored []byte
+	if !it.started {
+		it.started = true
+		it.key, stored = it.cursor.First()
+	} else {
+		it.key, stored = it.cursor.Next()
+	}
+	if it.key == nil {
+		return false
+	}
+	it.value, it.err = it.db.entryValue(it.tx, it.bucketName, it.key, stored)
+	return it.err == nil
+}
+
+// Key returns the key of the current entry.
+func (it *Iterator) Key() string {
+	return string(it.key)
+}
+
+// Value returns the value of the current entry, valid until the next call to Next.
+func (it *Iterator) Value() []byte {
+	return it.value
+}
+
+// Err returns the error that ended the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Close releases the read transaction. It is safe to call Close more than once.
+//
+// Returns:
+//   - error: Any error from rolling back the transaction
+func (it *Iterator) Close() error {
+	if it.tx == nil {
+		return nil
+	}
+	err := it.tx.Rollback()
+	it.tx = nil
+	it.cursor = nil
+	it.key, it.value = nil, nil
+	if err != nil && it.err == nil {
+		it.err = err
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package boltdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIteratorWalksBucketAndReleasesTransaction(t *testing.T) {
+	db := newTestDB(t, nil)
+	for _, k := range []string{"c", "a", "b"} {
+		mustSet(t, db, "data", k, []byte("v-"+k))
+	}
+
+	it, err := db.Iterator("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := db.db.Load().Stats().OpenTxN; n != 1 {
+		t.Fatalf("open transactions while iterating = %d, want 1", n)
+	}
+	var got []string
+	for it.Next() {
+		got = append(got, it.Key()+"="+string(it.Value()))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(got, " "); s != "a=v-a b=v-b c=v-c" {
+		t.Fatalf("iterated %q", s)
+	}
+
+	if err := it.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := db.db.Load().Stats().OpenTxN; n != 0 {
+		t.Fatalf("open transactions after Close = %d, want 0", n)
+	}
+	if it.Next() || it.Key() != "" || it.Value() != nil {
+		t.Fatal("closed iterator still yields entries")
+	}
+	if err := it.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestIteratorOverMissingBucket(t *testing.T) {
+	db := newTestDB(t, nil)
+	it, err := db.Iterator("missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	if it.Next() {
+		t.Fatal("iterator over a missing bucket yielded an entry")
+	}
+}
@@ -0,0 +1,241 @@
+package boltdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+// keyDictBucket is the reserved bucket holding the prefix dictionaries of every
+// KeyDictWrapper, with one nested bucket per data bucket.
+const keyDictBucket = "__keydict__"
+
+// KeyDictWrapper is a bucket wrapper that compresses keys by replacing their prefix
+// with a short numeric code. The prefix of a key is everything up to and including the
+// last occurrence of the separator. Each distinct prefix is assigned a code the first time
+// it is written, and the mapping is kept in a reserved bucket so it survives restarts.
+//
+// Codes are assigned in first-seen order, so stored keys no longer sort like the original
+// keys. ForEach and List visit entries grouped by prefix code rather than in key order,
+// and the wrapper deliberately offers no range or prefix scans.
+type KeyDictWrapper struct {
+	db         *BoltDatabase // The underlying database instance
+	bucketName string        // The bucket name this wrapper operates on
+	separator  string        // The separator marking the end of a key's prefix
+}
+
+// NewKeyDictWrapper creates a new key-compressing wrapper for a specific bucket.
+// All keys written to the bucket must go through the wrapper, since raw keys
+// written directly cannot be decoded.
+//
+// Parameters:
+//   - db: The BoltDatabase instance to wrap
+//   - bucketName: The name of the bucket this wrapper will operate on
+//   - separator: The separator that ends the compressed prefix of each key
+//
+// Returns:
+//   - *KeyDictWrapper: A new wrapper instance
+//   - error: An error if the separator is empty
+func NewKeyDictWrapper(db *BoltDatabase, bucketName, separator string) (*KeyDictWrapper, error) {
+	if separator == "" {
+		return nil, errors.New("separator is required")
+	}
+	return &KeyDictWrapper{db: db, bucketName: bucketName, separator: separator}, nil
+}
+
+// Set stores a value under the compressed form of key, assigning a new prefix code if needed.
+// The value is written like Set on the database, so chunking, the MaxBuckets option and
+// reserved bucket names apply, while indexes and watchers see the compressed key.
+//
+// Parameters:
+//   - key: The key to store
+//   - value: The value to store (as bytes)
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (w *KeyDictWrapper) Set(key string, value []byte) error {
+	if err := w.db.checkBucketName(w.bucketName); err != nil {
+		return err
+	}
+	return w.db.update(func(tx *bolt.Tx) error {
+		bucket, err := w.db.createBucketIfNotExists(tx, w.bucketName)
+		if err != nil {
+			return err
+		}
+		dict, err := w.dictBucket(tx)
+		if err != nil {
+			return err
+		}
+		encoded, err := w.encodeKey(dict, key, true)
+		if err != nil {
+			return err
+		}
+		return w.db.putKey(tx, bucket, w.bucketName, string(encoded), value)
+	})
+}
+
+// Get retrieves the value stored under key.
+//
+// Parameters:
+//   - key: The key to retrieve
+//
+// Returns:
+//   - []byte: A copy of the value associated with the key, or nil if not found
+//   - error: Any error that occurred during the operation
+func (w *KeyDictWrapper) Get(key string) ([]byte, error) {
+	var result []byte
+	err := w.db.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(w.bucketName))
+		dict := w.existingDictBucket(tx)
+		if bucket == nil || dict == nil {
+			return nil
+		}
+		encoded, err := w.encodeKey(dict, key, false)
+		if err != nil || encoded == nil {
+			return err
+		}
+		value, err := w.db.readValue(tx, w.bucketName, string(encoded), bucket.Get(encoded))
+		if err != nil || value == nil {
+			return err
+		}
+		result = append([]byte{}, value...)
+		return nil
+	})
+	return result, err
+}
+
+// Delete removes key from the bucket. Prefix codes are never reclaimed.
+//
+// Parameters:
+//   - key: The key to delete
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (w *KeyDictWrapper) Delete(key string) error {
+	return w.db.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(w.bucketName))
+		dict := w.existingDictBucket(tx)
+		if bucket == nil || dict == nil {
+			return nil
+		}
+		encoded, err := w.encodeKey(dict, key, false)
+		if err != nil || encoded == nil {
+			return err
+		}
+		return w.db.deleteKey(tx, bucket, w.bucketName, string(encoded))
+	})
+}
+
+// ForEach iterates over all entries with their keys restored.
+// Entries are visited grouped by prefix code, not in key order.
+// Values are only valid until fn returns; copy them to keep them.
+// Returning ErrStopIteration from fn stops the iteration without an error.
+// A panic in fn is returned as an error wrapping ErrCallbackPanic.
+//
+// Parameters:
+//   - fn: A function that will be called for each key-value pair
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (w *KeyDictWrapper) ForEach(fn func(key, value []byte) error) (err error) {
+	defer recoverCallback(&err)
+	err = w.db.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(w.bucketName))
+		dict := w.existingDictBucket(tx)
+		if bucket == nil || dict == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			key, err := w.decodeKey(dict, k)
+			if err != nil {
+				return err
+			}
+			value, err := w.db.entryValue(tx, w.bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			return fn([]byte(key), value)
+		})
+	})
+	return stopIteration(err)
+}
+
+// List returns all key-value pairs from the bucket with their keys restored.
+//
+// Returns:
+//   - map[string][]byte: A map of all key-value pairs in the bucket, with values copied
+//   - error: Any error that occurred during the operation
+func (w *KeyDictWrapper) List() (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	err := w.ForEach(func(key, value []byte) error {
+		result[string(key)] = append([]byte{}, value...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// dictBucket returns the dictionary for this wrapper's bucket, creating it if needed.
+func (w *KeyDictWrapper) dictBucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	root, err := tx.CreateBucketIfNotExists([]byte(keyDictBucket))
+	if err != nil {
+		return nil, err
+	}
+	return root.CreateBucketIfNotExists([]byte(w.bucketName))
+}
+
+// existingDictBucket returns the dictionary for this wrapper's bucket, or nil if none exists.
+func (w *KeyDictWrapper) existingDictBucket(tx *bolt.Tx) *bolt.Bucket {
+	root := tx.Bucket([]byte(keyDictBucket))
+	if root == nil {
+		return nil
+	}
+	return root.Bucket([]byte(w.bucketName))
+}
+
+// encodeKey replaces the prefix of key with its code. When assign is false and the prefix
+// has no code yet, nil is returned since no stored key can use it.
+func (w *KeyDictWrapper) encodeKey(dict *bolt.Bucket, key string, assign bool) ([]byte, error) {
+	prefix := ""
+	if i := strings.LastIndex(key, w.separator); i >= 0 {
+		prefix = key[:i+len(w.separator)]
+	}
+	suffix := key[len(prefix):]
+
+	prefixEntry := append([]byte("p"), prefix...)
+	code := dict.Get(prefixEntry)
+	if code == nil {
+		if !assign {
+			return nil, nil
+		}
+		seq, err := dict.NextSequence()
+		if err != nil {
+			return nil, err
+		}
+		code = binary.AppendUvarint(nil, seq)
+		if err := dict.Put(prefixEntry, code); err != nil {
+			return nil, err
+		}
+		if err := dict.Put(append([]byte("c"), code...), []byte(prefix)); err != nil {
+			return nil, err
+		}
+	}
+	return append(append([]byte(nil), code...), suffix...), nil
+}
+
+// decodeKey restores the original key from its stored form.
+func (w *KeyDictWrapper) decodeKey(dict *bolt.Bucket, stored []byte) (string, error) {
+	_, n := binary.Uvarint(stored)
+	if n <= 0 {
+		return "", errors.New("invalid compressed key")
+	}
+	prefix := dict.Get(append([]byte("c"), stored[:n]...))
+	if prefix == nil {
+		return "", errors.New("unknown key prefix code")
+	}
+	return string(prefix) + string(stored[n:]), nil
+}
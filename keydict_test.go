@@ -0,0 +1,137 @@
+package boltdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestKeyDictWrapperCompressesKeys(t *testing.T) {
+	db := newTestDB(t, nil)
+	w, err := NewKeyDictWrapper(db, "events", ":")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefixes := []string{"tenant-0001/service-billing/events:", "tenant-0002/service-shipping/events:"}
+	want := make(map[string]string)
+	logical := 0
+	for _, prefix := range prefixes {
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("%s%04d", prefix, i)
+			value := "v" + key
+			if err := w.Set(key, []byte(value)); err != nil {
+				t.Fatal(err)
+			}
+			want[key] = value
+			logical += len(key)
+		}
+	}
+
+	for key, value := range want {
+		if got, err := w.Get(key); err != nil || string(got) != value {
+			t.Fatalf("Get %s = %q, %v; want %q", key, got, err, value)
+		}
+	}
+	all, err := w.List()
+	if err != nil || len(all) != len(want) {
+		t.Fatalf("List = %d entries, %v; want %d", len(all), err, len(want))
+	}
+	for key, value := range all {
+		if want[key] != string(value) {
+			t.Fatalf("List %s = %q, want %q", key, value, want[key])
+		}
+	}
+
+	stored := 0
+	err = db.db.Load().View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("events")).ForEach(func(k, v []byte) error {
+			if strings.Contains(string(k), "tenant") {
+				t.Errorf("stored key %q still holds its prefix", k)
+			}
+			stored += len(k)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored*4 > logical {
+		t.Fatalf("stored key bytes = %d, want well below the %d logical bytes", stored, logical)
+	}
+
+	if err := w.Delete(prefixes[0] + "0000"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := w.Get(prefixes[0] + "0000"); err != nil || got != nil {
+		t.Fatalf("Get after Delete = %q, %v; want nil", got, err)
+	}
+	if got, err := w.Get("unknown-prefix:1"); err != nil || got != nil {
+		t.Fatalf("Get with unknown prefix = %q, %v; want nil", got, err)
+	}
+}
+
+func TestNewKeyDictWrapperRequiresSeparator(t *testing.T) {
+	if _, err := NewKeyDictWrapper(newTestDB(t, nil), "b", ""); err == nil {
+		t.Fatal("NewKeyDictWrapper accepted an empty separator")
+	}
+}
+
+func TestKeyDictWrapperUsesSharedWritePath(t *testing.T) {
+	db := newTestDB(t, &Options{MaxBuckets: 1})
+	mustSet(t, db, "other", "k", []byte("v"))
+
+	reserved, _ := NewKeyDictWrapper(db, "__meta__:x", ":")
+	if err := reserved.Set("a:1", []byte("v")); !errors.Is(err, ErrReservedBucket) {
+		t.Fatalf("Set on reserved bucket = %v, want ErrReservedBucket", err)
+	}
+	limited, _ := NewKeyDictWrapper(db, "events", ":")
+	if err := limited.Set("a:1", []byte("v")); !errors.Is(err, ErrTooManyBuckets) {
+		t.Fatalf("Set beyond MaxBuckets = %v, want ErrTooManyBuckets", err)
+	}
+
+	db = newTestDB(t, nil)
+	w, _ := NewKeyDictWrapper(db, "events", ":")
+	if err := w.Set("tenant:0", []byte("small")); err != nil {
+		t.Fatal(err)
+	}
+	small, err := w.Get("tenant:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetChunkSize(16)
+	large := []byte("a value spanning several chunks")
+	if err := w.Set("tenant:1", large); err != nil {
+		t.Fatal(err)
+	}
+	got, err := w.Get("tenant:1")
+	if err != nil || string(got) != string(large) {
+		t.Fatalf("Get chunked value = %q, %v", got, err)
+	}
+	all, err := w.List()
+	if err != nil || string(all["tenant:1"]) != string(large) {
+		t.Fatalf("List chunked value = %q, %v", all["tenant:1"], err)
+	}
+
+	// Values returned by Get and List stay valid after later writes and remaps.
+	db.SetChunkSize(0)
+	for i := 0; i < 200; i++ {
+		if err := w.Set(fmt.Sprintf("tenant:%d", i+2), bytes.Repeat([]byte("x"), 512)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(small) != "small" || string(all["tenant:0"]) != "small" || string(all["tenant:1"]) != string(large) {
+		t.Fatalf("returned values changed: %q, %q, %q", small, all["tenant:0"], all["tenant:1"])
+	}
+
+	if err := w.Delete("tenant:1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := chunkCount(t, db, "events"); n != 0 {
+		t.Fatalf("Delete left %d chunks", n)
+	}
+}
@@ -0,0 +1,83 @@
+package boltdb
+
+import (
+	"strings"
+)
+
+const (
+	// KEY_DELIMITER separates the parts of a composite key built by JoinKey.
+	KEY_DELIMITER = ':'
+
+	// KEY_ESCAPE escapes delimiters and itself inside composite key parts.
+	KEY_ESCAPE = '\\'
+)
+
+// JoinKey builds a composite key from parts separated by KEY_DELIMITER.
+// Delimiter and escape characters inside a part are escaped with KEY_ESCAPE,
+// so SplitKey recovers the original parts. The one exception is JoinKey() with no
+// parts, which returns the same empty key as JoinKey("") and splits back to [""].
+//
+// Parameters:
+//   - parts: The key parts; at least one is needed for SplitKey to round-trip
+//
+// Returns:
+//   - string: The composite key
+func JoinKey(parts ...string) string {
+	var sb strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			sb.WriteByte(KEY_DELIMITER)
+		}
+		for j := 0; j < len(part); j++ {
+			if part[j] == KEY_DELIMITER || part[j] == KEY_ESCAPE {
+				sb.WriteByte(KEY_ESCAPE)
+			}
+			sb.WriteByte(part[j])
+		}
+	}
+	return sb.String()
+}
+
+// SplitKey splits a composite key built by JoinKey back into its parts,
+// removing the escaping. It always returns at least one part, so the empty
+// key splits to [""].
+//
+// Parameters:
+//   - key: The composite key
+//
+// Returns:
+//   - []string: The key parts
+func SplitKey(key string) []string {
+	parts := make([]string, 0, strings.Count(key, string(KEY_DELIMITER))+1)
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case KEY_ESCAPE:
+			if i+1 < len(key) {
+				i++
+			}
+			sb.WriteByte(key[i])
+		case KEY_DELIMITER:
+			parts = append(parts, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(key[i])
+		}
+	}
+	return append(parts, sb.String())
+}
+
+// ScanKeyParts iterates over all entries in the specified bucket, passing each
+// composite key already split into its parts.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to iterate over
+//   - fn: A function that will be called with the parsed key parts and value
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) ScanKeyParts(bucketName string, fn func(parts []string, value []byte) error) error {
+	return b.ForEach(bucketName, func(key, value []byte) error {
+		return fn(SplitKey(string(key)), value)
+	})
+}
@@ -0,0 +1,49 @@
+package boltdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinKeySplitKeyRoundTrip(t *testing.T) {
+	cases := [][]string{
+		{"tenant", "user", "field"},
+		{"a:b", "c"},
+		{`back\slash`, `trailing\`, `:`},
+		{`\:`, "", "::"},
+		{""},
+	}
+	for _, parts := range cases {
+		key := JoinKey(parts...)
+		if got := SplitKey(key); !reflect.DeepEqual(got, parts) {
+			t.Errorf("SplitKey(%q) = %q, want %q", key, got, parts)
+		}
+	}
+
+	if got, want := JoinKey("a:b", `c\`), `a\:b:c\\`; got != want {
+		t.Fatalf("JoinKey escaped to %q, want %q", got, want)
+	}
+	if JoinKey() != JoinKey("") {
+		t.Fatalf("JoinKey() = %q, want the same key as JoinKey(\"\")", JoinKey())
+	}
+	if got := SplitKey(JoinKey()); !reflect.DeepEqual(got, []string{""}) {
+		t.Fatalf("SplitKey(JoinKey()) = %q, want [\"\"]", got)
+	}
+}
+
+func TestScanKeyPartsSplitsKeys(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "data", JoinKey("t1", "u:1"), []byte("v"))
+
+	var got []string
+	err := db.ScanKeyParts("data", func(parts []string, value []byte) error {
+		got = parts
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"t1", "u:1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
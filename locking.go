@@ -0,0 +1,44 @@
+package boltdb
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/boltdb/bolt"
+)
+
+// bucketLock returns the application-level mutex guarding the named bucket.
+func (b *BoltDatabase) bucketLock(bucketName string) *sync.Mutex {
+	lck, _ := b.bucketLocks.LoadOrStore(bucketName, &sync.Mutex{})
+	return lck.(*sync.Mutex)
+}
+
+// WithBuckets runs fn in a read-write transaction while holding the application-level
+// locks of the given buckets.
+// Bolt already serializes write transactions, but callers that also guard buckets with
+// their own per-bucket locks can deadlock when two goroutines lock the same buckets in
+// different orders. WithBuckets always acquires the locks in sorted name order, so any
+// number of callers can request overlapping bucket sets safely.
+//
+// Parameters:
+//   - buckets: The names of the buckets to lock; duplicates are ignored
+//   - fn: A function that will be called with the read-write transaction
+//
+// Returns:
+//   - error: Any error returned by fn or the transaction
+func (b *BoltDatabase) WithBuckets(buckets []string, fn func(tx *bolt.Tx) error) error {
+	names := slices.Clone(buckets)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	for _, name := range names {
+		b.bucketLock(name).Lock()
+	}
+	defer func() {
+		for i := len(names) - 1; i >= 0; i-- {
+			b.bucketLock(names[i]).Unlock()
+		}
+	}()
+
+	return b.update(fn)
+}
@@ -0,0 +1,47 @@
+package boltdb
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestWithBucketsOppositeOrderDoesNotDeadlock(t *testing.T) {
+	db := newTestDB(t, nil)
+	orders := [][]string{{"a", "b"}, {"b", "a", "b"}}
+
+	var wg sync.WaitGroup
+	for _, order := range orders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				err := db.WithBuckets(order, func(tx *bolt.Tx) error {
+					for _, name := range order {
+						if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					t.Errorf("WithBuckets(%v): %v", order, err)
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("WithBuckets deadlocked")
+	}
+}
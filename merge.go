@@ -0,0 +1,149 @@
+package boltdb
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// Key set operation constants for BucketKeySetOp
+const (
+	KeySetIntersect  = "intersect"  // Keys present in both buckets
+	KeySetUnion      = "union"      // Keys present in either bucket
+	KeySetDifference = "difference" // Keys present in the first bucket but not the second
+)
+
+// PriorityMerge iterates over the union of keys in several buckets in sorted key order,
+// emitting each key once with the value from the highest-priority bucket holding it.
+// Buckets are listed from highest to lowest priority; missing buckets are skipped.
+// All cursors advance together in a single read transaction, so the merge streams
+// without loading the buckets into memory. Returning ErrStopIteration from fn stops
+// the merge without an error.
+//
+// Parameters:
+//   - buckets: The bucket names, highest priority first
+//   - fn: A function called for each key with its winning value and source bucket
+//
+// Returns:
+//   - error: Any error returned by fn or the transaction
+func (b *BoltDatabase) PriorityMerge(buckets []string, fn func(key string, value []byte, fromBucket string) error) (err error) {
+	defer recoverCallback(&err)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		type head struct {
+			name   string
+			cursor *bolt.Cursor
+			key    []byte
+			value  []byte
+		}
+
+		heads := make([]*head, 0, len(buckets))
+		for _, name := range buckets {
+			bucket := tx.Bucket([]byte(name))
+			if bucket == nil {
+				continue
+			}
+			h := &head{name: name, cursor: bucket.Cursor()}
+			h.key, h.value = h.cursor.First()
+			heads = append(heads, h)
+		}
+
+		for {
+			// The first head holding the smallest key wins, since heads keep priority order.
+			var winner *head
+			for _, h := range heads {
+				if h.key != nil && (winner == nil || bytes.Compare(h.key, winner.key) < 0) {
+					winner = h
+				}
+			}
+			if winner == nil {
+				return nil
+			}
+
+			key := string(winner.key)
+			value, err := b.entryValue(tx, winner.name, winner.key, winner.value)
+			if err != nil {
+				return err
+			}
+			if err := fn(key, value, winner.name); err != nil {
+				return err
+			}
+			for _, h := range heads {
+				if h.key != nil && string(h.key) == key {
+					h.key, h.value = h.cursor.Next()
+				}
+			}
+		}
+	})
+	return stopIteration(err)
+}
+
+// BucketKeySetOp treats two buckets as sets of keys and computes their intersection,
+// union or difference. Both cursors are merged in a single read transaction, so the
+// result is produced in one pass and is sorted. Missing buckets are treated as empty.
+//
+// Parameters:
+//   - bucketA: The name of the first bucket
+//   - bucketB: The name of the second bucket
+//   - op: One of KeySetIntersect, KeySetUnion or KeySetDifference
+//
+// Returns:
+//   - []string: The resulting keys in sorted order
+//   - error: An error if op is unknown or the operation fails
+func (b *BoltDatabase) BucketKeySetOp(bucketA, bucketB, op string) ([]string, error) {
+	switch op {
+	case KeySetIntersect, KeySetUnion, KeySetDifference:
+	default:
+		return nil, fmt.Errorf("unknown key set operation %q", op)
+	}
+
+	result := make([]string, 0)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		var ka, kb []byte
+		var ca, cb *bolt.Cursor
+		if bucket := tx.Bucket([]byte(bucketA)); bucket != nil {
+			ca = bucket.Cursor()
+			ka, _ = ca.First()
+		}
+		if bucket := tx.Bucket([]byte(bucketB)); bucket != nil {
+			cb = bucket.Cursor()
+			kb, _ = cb.First()
+		}
+
+		for ka != nil || kb != nil {
+			cmp := 0
+			switch {
+			case ka == nil:
+				cmp = 1
+			case kb == nil:
+				cmp = -1
+			default:
+				cmp = bytes.Compare(ka, kb)
+			}
+
+			switch {
+			case cmp < 0:
+				if op != KeySetIntersect {
+					result = append(result, string(ka))
+				}
+				ka, _ = ca.Next()
+			case cmp > 0:
+				if op == KeySetUnion {
+					result = append(result, string(kb))
+				}
+				kb, _ = cb.Next()
+			default:
+				if op != KeySetDifference {
+					result = append(result, string(ka))
+				}
+				ka, _ = ca.Next()
+				kb, _ = cb.Next()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package boltdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPriorityMergeTakesHighestPriorityOnce(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "high", "b", []byte("high-b"))
+	mustSet(t, db, "mid", "a", []byte("mid-a"))
+	mustSet(t, db, "mid", "b", []byte("mid-b"))
+	mustSet(t, db, "mid", "c", []byte("mid-c"))
+	mustSet(t, db, "low", "a", []byte("low-a"))
+	mustSet(t, db, "low", "c", []byte("low-c"))
+	mustSet(t, db, "low", "d", []byte("low-d"))
+
+	var got []string
+	err := db.PriorityMerge([]string{"high", "missing", "mid", "low"}, func(key string, value []byte, from string) error {
+		got = append(got, key+"="+string(value)+"@"+from)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a=mid-a@mid b=high-b@high c=mid-c@mid d=low-d@low"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("got %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestBucketKeySetOp(t *testing.T) {
+	db := newTestDB(t, nil)
+	for _, k := range []string{"a", "b", "c", "e"} {
+		mustSet(t, db, "left", k, []byte("v"))
+	}
+	for _, k := range []string{"b", "d", "e", "f"} {
+		mustSet(t, db, "right", k, []byte("v"))
+	}
+
+	cases := []struct {
+		a, b, op, want string
+	}{
+		{"left", "right", KeySetIntersect, "b,e"},
+		{"left", "right", KeySetUnion, "a,b,c,d,e,f"},
+		{"left", "right", KeySetDifference, "a,c"},
+		{"right", "left", KeySetDifference, "d,f"},
+		{"left", "missing", KeySetIntersect, ""},
+		{"missing", "right", KeySetUnion, "b,d,e,f"},
+	}
+	for _, c := range cases {
+		keys, err := db.BucketKeySetOp(c.a, c.b, c.op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(keys, ","); got != c.want {
+			t.Errorf("%s %s %s = %q, want %q", c.a, c.op, c.b, got, c.want)
+		}
+	}
+
+	if _, err := db.BucketKeySetOp("left", "right", "xor"); err == nil {
+		t.Fatal("expected an error for an unknown operation")
+	}
+}
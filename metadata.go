@@ -0,0 +1,107 @@
+package boltdb
+
+import (
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+// metaBucketPrefix prefixes the reserved buckets holding per-key metadata.
+// The metadata of bucket "users" lives in "__meta__:users", keyed by the same keys.
+const metaBucketPrefix = "__meta__:"
+
+// SetMetadata attaches metadata to a key of the specified bucket.
+// Metadata is stored in a reserved sidecar bucket and is not removed by Delete;
+// use GCMetadata to clean up metadata left behind by deleted keys.
+//
+// Parameters:
+//   - bucketName: The name of the bucket holding the key
+//   - key: The key the metadata describes
+//   - meta: The metadata to store
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) SetMetadata(bucketName, key string, meta []byte) error {
+	return b.update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(metaBucketPrefix + bucketName))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), meta)
+	})
+}
+
+// GetMetadata retrieves the metadata attached to a key.
+//
+// Parameters:
+//   - bucketName: The name of the bucket holding the key
+//   - key: The key the metadata describes
+//
+// Returns:
+//   - []byte: The metadata, or nil if none is stored
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) GetMetadata(bucketName, key string) ([]byte, error) {
+	var result []byte
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metaBucketPrefix + bucketName))
+		if bucket == nil {
+			return nil
+		}
+		if v := bucket.Get([]byte(key)); v != nil {
+			result = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// GCMetadata removes metadata entries whose key no longer exists in its primary bucket,
+// such as metadata left behind when a key is removed with the plain Delete.
+// All metadata buckets are scanned and cleaned in a single read-write transaction.
+//
+// Returns:
+//   - int: The number of metadata entries removed
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) GCMetadata() (int, error) {
+	removed := 0
+	err := b.update(func(tx *bolt.Tx) error {
+		var metaBuckets []string
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if strings.HasPrefix(string(name), metaBucketPrefix) {
+				metaBuckets = append(metaBuckets, string(name))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, name := range metaBuckets {
+			meta := tx.Bucket([]byte(name))
+			primary := tx.Bucket([]byte(strings.TrimPrefix(name, metaBucketPrefix)))
+
+			// Collect first: deleting while iterating invalidates the cursor.
+			var stale [][]byte
+			err := meta.ForEach(func(k, _ []byte) error {
+				if primary == nil || !hasKey(primary, k) {
+					stale = append(stale, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, k := range stale {
+				if err := meta.Delete(k); err != nil {
+					return err
+				}
+			}
+			removed += len(stale)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
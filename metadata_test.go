@@ -0,0 +1,68 @@
+package boltdb
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestGCMetadataRemovesOrphanedEntries(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "users", "alice", []byte("1"))
+	mustSet(t, db, "users", "bob", []byte("2"))
+	for _, key := range []string{"alice", "bob"} {
+		if err := db.SetMetadata("users", key, []byte("meta-"+key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if meta, err := db.GetMetadata("users", "alice"); err != nil || string(meta) != "meta-alice" {
+		t.Fatalf("GetMetadata = %q, %v", meta, err)
+	}
+
+	if err := db.Delete("users", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := db.GCMetadata()
+	if err != nil || removed != 1 {
+		t.Fatalf("GCMetadata = %d, %v; want 1", removed, err)
+	}
+	if meta, _ := db.GetMetadata("users", "bob"); meta != nil {
+		t.Fatalf("stale metadata survived: %q", meta)
+	}
+	if meta, _ := db.GetMetadata("users", "alice"); string(meta) != "meta-alice" {
+		t.Fatalf("live metadata removed, got %q", meta)
+	}
+
+	if removed, err := db.GCMetadata(); err != nil || removed != 0 {
+		t.Fatalf("second GCMetadata = %d, %v; want 0", removed, err)
+	}
+}
+
+func TestGCMetadataKeepsEntriesOfEmptyValues(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "flags", "on", []byte{})
+	if err := db.SetMetadata("flags", "on", []byte("m")); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Update("flags", func(_ *bolt.Tx, bucket *bolt.Bucket) error {
+		_, err := bucket.CreateBucket([]byte("nested"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetMetadata("flags", "nested", []byte("m")); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := db.GCMetadata()
+	if err != nil || removed != 1 {
+		t.Fatalf("GCMetadata = %d, %v; want only the nested bucket's entry removed", removed, err)
+	}
+	if meta, err := db.GetMetadata("flags", "on"); err != nil || string(meta) != "m" {
+		t.Fatalf("GetMetadata of an empty value = %q, %v; want m", meta, err)
+	}
+	if meta, err := db.GetMetadata("missing", "k"); err != nil || meta != nil {
+		t.Fatalf("GetMetadata of a missing bucket = %q, %v; want nil", meta, err)
+	}
+}
@@ -0,0 +1,281 @@
+package boltdb
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+// MAX_REPORTED_DIFFERENCES caps the number of locations returned by EqualContents.
+const MAX_REPORTED_DIFFERENCES = 100
+
+// migrateEntry is a transformed entry waiting to be written by Migrate.
+type migrateEntry struct {
+	bucket []byte
+	key    []byte
+	value  []byte
+}
+
+// Migrate copies every bucket and key from src into dst, optionally transforming entries.
+// Entries are read in a single read transaction on src and written to dst in chunks of
+// MAX_SEQUENTIAL_OPERATIONS per transaction, so a failure part-way leaves dst partially
+// populated. Values are written like Set, so chunked values are reassembled before
+// transform sees them and chunked again according to dst's chunk size, and dst's indexes
+// and insertion order are maintained. The chunk, metadata and insertion order sidecars of
+// src are skipped, since they describe src's keys. Nested buckets, such as those of
+// index buckets, and the other reserved buckets, such as the KeyDictWrapper dictionaries,
+// are copied as is with their sequences, each in one transaction, without transform.
+// src and dst must be different databases, since writing to dst happens while src's
+// read transaction is open.
+//
+// Parameters:
+//   - src: The database to read from
+//   - dst: The database to write to
+//   - transform: Maps each top-level entry to its new key and value, or drops it by
+//     returning keep=false; nil copies entries unchanged
+//
+// Returns:
+//   - error: An error if src and dst are the same database, or any error that occurred
+//     while reading or writing
+func Migrate(src, dst *BoltDatabase, transform func(bucket, key string, value []byte) (newKey string, newValue []byte, keep bool)) error {
+	if src == dst || src.db.Load() == dst.db.Load() {
+		return errors.New("source and destination must be different databases")
+	}
+	chunk := make([]migrateEntry, 0, MAX_SEQUENTIAL_OPERATIONS)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := dst.update(func(tx *bolt.Tx) error {
+			for _, entry := range chunk {
+				bucket, err := dst.createBucketIfNotExists(tx, string(entry.bucket))
+				if err != nil {
+					return err
+				}
+				if err := dst.putKey(tx, bucket, string(entry.bucket), string(entry.key), entry.value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		chunk = chunk[:0]
+		return err
+	}
+
+	err := src.db.Load().View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if isSidecarBucket(string(name)) {
+				return nil
+			}
+			if src.isReserved(string(name)) {
+				return dst.update(func(dstTx *bolt.Tx) error {
+					dstBucket, err := dstTx.CreateBucketIfNotExists(name)
+					if err != nil {
+						return err
+					}
+					return copyBucket(dstBucket, bucket)
+				})
+			}
+
+			// Create the bucket with its sequence up front, so empty buckets and IDs
+			// reserved with ReserveIDs carry over.
+			err := dst.update(func(dstTx *bolt.Tx) error {
+				dstBucket, err := dst.createBucketIfNotExists(dstTx, string(name))
+				if err != nil {
+					return err
+				}
+				return dstBucket.SetSequence(bucket.Sequence())
+			})
+			if err != nil {
+				return err
+			}
+
+			return bucket.ForEach(func(k, v []byte) error {
+				if v == nil {
+					nested := bucket.Bucket(k)
+					if nested == nil {
+						return nil
+					}
+					return dst.update(func(dstTx *bolt.Tx) error {
+						dstNested, err := dstTx.Bucket(name).CreateBucketIfNotExists(k)
+						if err != nil {
+							return err
+						}
+						return copyBucket(dstNested, nested)
+					})
+				}
+				v, err := src.entryValue(tx, string(name), k, v)
+				if err != nil {
+					return err
+				}
+
+				key, value := string(k), v
+				if transform != nil {
+					var keep bool
+					key, value, keep = transform(string(name), string(k), v)
+					if !keep {
+						return nil
+					}
+				}
+
+				chunk = append(chunk, migrateEntry{
+					bucket: append([]byte(nil), name...),
+					key:    []byte(key),
+					value:  append([]byte(nil), value...),
+				})
+				if len(chunk) >= MAX_SEQUENTIAL_OPERATIONS {
+					return flush()
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// isSidecarBucket reports whether name is a sidecar bucket describing the keys of a data
+// bucket, which Migrate rebuilds rather than copies.
+func isSidecarBucket(name string) bool {
+	for _, prefix := range sidecarPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// EqualContents compares every bucket, nested bucket and key/value pair of two databases,
+// for example to verify a copy made by Migrate or Backup. Each database is read in a
+// single read transaction. Differences are reported as slash-separated locations such as
+// "bucket/key" or "bucket/nested/key"; a bucket present on one side only is reported by
+// its own location. Comparison stops once MAX_REPORTED_DIFFERENCES locations are found.
+//
+// Parameters:
+//   - a: The first database
+//   - b: The second database
+//
+// Returns:
+//   - bool: True if both databases hold exactly the same data
+//   - []string: The differing locations, in key order, at most MAX_REPORTED_DIFFERENCES
+//   - error: Any error that occurred while reading either database
+func EqualContents(a, b *BoltDatabase) (bool, []string, error) {
+	diffs := make([]string, 0)
+	err := a.db.Load().View(func(txA *bolt.Tx) error {
+		return b.db.Load().View(func(txB *bolt.Tx) error {
+			diffContainers("", txA, txB, &diffs)
+			return nil
+		})
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return len(diffs) == 0, diffs, nil
+}
+
+// bucketContainer is implemented by both *bolt.Tx and *bolt.Bucket.
+type bucketContainer interface {
+	Cursor() *bolt.Cursor
+	Bucket(name []byte) *bolt.Bucket
+}
+
+// diffContainers walks a and b in key order, appending the locations that differ to diffs.
+func diffContainers(path string, a, b bucketContainer, diffs *[]string) {
+	ca, cb := a.Cursor(), b.Cursor()
+	ka, va := ca.First()
+	kb, vb := cb.First()
+	for (ka != nil || kb != nil) && len(*diffs) < MAX_REPORTED_DIFFERENCES {
+		cmp := bytes.Compare(ka, kb)
+		if ka == nil {
+			cmp = 1
+		} else if kb == nil {
+			cmp = -1
+		}
+
+		switch {
+		case cmp < 0:
+			*diffs = append(*diffs, path+string(ka))
+			ka, va = ca.Next()
+		case cmp > 0:
+			*diffs = append(*diffs, path+string(kb))
+			kb, vb = cb.Next()
+		default:
+			nestedA, nestedB := a.Bucket(ka), b.Bucket(kb)
+			switch {
+			case nestedA != nil && nestedB != nil:
+				diffContainers(path+string(ka)+"/", nestedA, nestedB, diffs)
+			case nestedA != nil || nestedB != nil || !bytes.Equal(va, vb):
+				*diffs = append(*diffs, path+string(ka))
+			}
+			ka, va = ca.Next()
+			kb, vb = cb.Next()
+		}
+	}
+}
+
+// ImportBucketFrom merges a bucket of another database into a bucket of this one, for
+// consolidating shards. Keys missing from dstBucket are copied as is. For keys present in
+// both, resolve chooses the value to keep; a nil resolve lets the incoming value win, and
+// a nil result keeps the existing value. Entries are read in pages and written in chunks
+// of MAX_SEQUENTIAL_OPERATIONS per transaction, so a failure part-way leaves the import
+// partially applied. src may be this database. Nested buckets are not copied.
+// Reserved bucket names are rejected with ErrReservedBucket.
+//
+// Parameters:
+//   - src: The database to import from
+//   - srcBucket: The bucket of src to read
+//   - dstBucket: The bucket of this database to write, created if needed
+//   - resolve: Chooses the value for a key present on both sides, or nil
+//
+// Returns:
+//   - error: Any error that occurred while reading or writing
+func (b *BoltDatabase) ImportBucketFrom(src *BoltDatabase, srcBucket, dstBucket string, resolve func(key string, existing, incoming []byte) []byte) error {
+	if err := b.checkBucketName(dstBucket); err != nil {
+		return err
+	}
+
+	after := ""
+	for {
+		keys, values, next, err := src.Page(srcBucket, after, MAX_SEQUENTIAL_OPERATIONS)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		err = b.update(func(tx *bolt.Tx) error {
+			bucket, err := b.createBucketIfNotExists(tx, dstBucket)
+			if err != nil {
+				return err
+			}
+			for i, key := range keys {
+				value := values[i]
+				if resolve != nil && hasKey(bucket, []byte(key)) {
+					existing, err := b.readValue(tx, dstBucket, key, bucket.Get([]byte(key)))
+					if err != nil {
+						return err
+					}
+					if value = resolve(key, append([]byte{}, existing...), value); value == nil {
+						continue
+					}
+				}
+				if err := b.putKey(tx, bucket, dstBucket, key, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		after = next
+	}
+}
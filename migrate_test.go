@@ -0,0 +1,231 @@
+package boltdb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// contents returns every key/value of every bucket in db as "bucket/key=value" strings.
+func contents(t *testing.T, db *BoltDatabase) []string {
+	t.Helper()
+	var out []string
+	for _, bucket := range bucketNames(t, db) {
+		err := db.ForEach(bucket, func(k, v []byte) error {
+			out = append(out, fmt.Sprintf("%s/%s=%s", bucket, k, v))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return out
+}
+
+func TestMigrateCopiesEverything(t *testing.T) {
+	src, dst := newTestDB(t, nil), newTestDB(t, nil)
+	// Enough entries to need more than one destination transaction.
+	err := src.db.Load().Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("users"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < MAX_SEQUENTIAL_OPERATIONS+5; i++ {
+			if err := bucket.Put([]byte(fmt.Sprintf("u%05d", i)), []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, src, "groups", "g1", []byte("admins"))
+
+	if err := Migrate(src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	want, got := contents(t, src), contents(t, dst)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("dst has %d entries, want %d", len(got), len(want))
+	}
+}
+
+func TestMigrateTransformsAndDrops(t *testing.T) {
+	src, dst := newTestDB(t, nil), newTestDB(t, nil)
+	mustSet(t, src, "users", "alice", []byte("1"))
+	mustSet(t, src, "users", "bob", []byte("2"))
+	mustSet(t, src, "users", "carol", []byte("3"))
+
+	err := Migrate(src, dst, func(bucket, key string, value []byte) (string, []byte, bool) {
+		if key == "bob" {
+			return "", nil, false
+		}
+		return "user:" + key, append([]byte("n="), value...), true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(contents(t, dst), " ")
+	if want := "users/user:alice=n=1 users/user:carol=n=3"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestEqualContentsReportsDifferences(t *testing.T) {
+	src, dst := newTestDB(t, nil), newTestDB(t, nil)
+	mustSet(t, src, "users", "alice", []byte("1"))
+	mustSet(t, src, "users", "bob", []byte("2"))
+	mustSet(t, src, "groups", "admins", []byte("alice"))
+	if err := Migrate(src, dst, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	equal, diffs, err := EqualContents(src, dst)
+	if err != nil || !equal || len(diffs) != 0 {
+		t.Fatalf("exact copy: equal=%v diffs=%v err=%v", equal, diffs, err)
+	}
+
+	mustSet(t, dst, "users", "bob", []byte("changed"))
+	mustSet(t, dst, "extra", "k", []byte("v"))
+	equal, diffs, err = EqualContents(src, dst)
+	if err != nil || equal {
+		t.Fatalf("changed copy reported equal: %v", err)
+	}
+	if got := strings.Join(diffs, " "); got != "extra users/bob" {
+		t.Fatalf("diffs = %q, want extra users/bob", got)
+	}
+}
+
+func TestEqualContentsCapsReportedDifferences(t *testing.T) {
+	a, b := newTestDB(t, nil), newTestDB(t, nil)
+	err := a.db.Load().Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("data"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < MAX_REPORTED_DIFFERENCES*2; i++ {
+			if err := bucket.Put([]byte(fmt.Sprintf("k%04d", i)), []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, b, "data", "other", []byte("v"))
+
+	equal, diffs, err := EqualContents(a, b)
+	if err != nil || equal || len(diffs) != MAX_REPORTED_DIFFERENCES {
+		t.Fatalf("equal=%v, %d diffs, err=%v; want %d diffs", equal, len(diffs), err, MAX_REPORTED_DIFFERENCES)
+	}
+}
+
+func TestImportBucketFromResolvesCollisions(t *testing.T) {
+	src, dst := newTestDB(t, nil), newTestDB(t, nil)
+	mustSet(t, src, "shard", "a", []byte("src-a"))
+	mustSet(t, src, "shard", "b", []byte("src-b"))
+	mustSet(t, dst, "merged", "b", []byte("dst-b"))
+	mustSet(t, dst, "merged", "c", []byte("dst-c"))
+
+	resolve := func(key string, existing, incoming []byte) []byte {
+		return []byte(string(existing) + "+" + string(incoming))
+	}
+	if err := dst.ImportBucketFrom(src, "shard", "merged", resolve); err != nil {
+		t.Fatal(err)
+	}
+	got := bucketContents(t, dst, "merged")
+	want := map[string]string{"a": "src-a", "b": "dst-b+src-b", "c": "dst-c"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("merged = %v, want %v", got, want)
+	}
+
+	if err := dst.ImportBucketFrom(src, "shard", "merged", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := bucketContents(t, dst, "merged"); got["b"] != "src-b" {
+		t.Fatalf("b = %q with a nil resolver, want the incoming value", got["b"])
+	}
+}
+
+func TestMigrateRejectsSameDatabase(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "b", "k", []byte("v"))
+
+	if err := Migrate(db, db, nil); err == nil {
+		t.Fatal("Migrate into the source database succeeded")
+	}
+}
+
+func TestMigrateRenamesKeysAndRebuildsSidecars(t *testing.T) {
+	src, dst := newTestDB(t, nil), newTestDB(t, nil)
+	src.EnableInsertionOrder("users")
+	dst.EnableInsertionOrder("users")
+	src.SetChunkSize(8)
+	mustSet(t, src, "users", "bob", []byte("a value spanning chunks"))
+	mustSet(t, src, "users", "alice", []byte("1"))
+	src.SetChunkSize(0)
+	if err := src.SetMetadata("users", "bob", []byte("m")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := src.ReserveIDs("users", 5); err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, src, "groups", "admins", []byte("alice"))
+	if err := src.CreateIndex("groups", "by-member", func(_ string, value []byte) string { return string(value) }); err != nil {
+		t.Fatal(err)
+	}
+	dict, err := NewKeyDictWrapper(src, "events", ":")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dict.Set("tenant:1", []byte("e")); err != nil {
+		t.Fatal(err)
+	}
+
+	err = Migrate(src, dst, func(bucket, key string, value []byte) (string, []byte, bool) {
+		if bucket == "users" {
+			key = "user:" + key
+		}
+		return key, value, true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range bucketNames(t, dst) {
+		if isSidecarBucket(name) && !strings.HasPrefix(name, orderBucketPrefix+"users") {
+			t.Fatalf("src sidecar %s was copied", name)
+		}
+	}
+	entries, err := dst.ListInsertionOrder("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Entries are copied in key order, which becomes their insertion order in dst.
+	if len(entries) != 2 || entries[0].Key != "user:alice" || entries[1].Key != "user:bob" || string(entries[1].Value) != "a value spanning chunks" {
+		t.Fatalf("dst insertion order = %v, want user:alice then user:bob", entries)
+	}
+	if got, err := dst.GetMetadata("users", "bob"); err != nil || got != nil {
+		t.Fatalf("metadata of the old key = %q, %v; want none", got, err)
+	}
+	if start, _, err := dst.ReserveIDs("users", 1); err != nil || start != 6 {
+		t.Fatalf("ReserveIDs in dst = %d, %v; want 6", start, err)
+	}
+
+	// Nested index buckets and the key dictionary are copied as is.
+	if keys, err := dst.QueryByIndex("by-member", "alice"); err != nil || len(keys) != 1 || keys[0] != "admins" {
+		t.Fatalf("copied index = %v, %v; want [admins]", keys, err)
+	}
+	dstDict, err := NewKeyDictWrapper(dst, "events", ":")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := dstDict.Get("tenant:1"); err != nil || string(got) != "e" {
+		t.Fatalf("KeyDictWrapper Get in dst = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,108 @@
+package boltdb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrTooManyBuckets is returned when creating a bucket would exceed Options.MaxBuckets.
+var ErrTooManyBuckets = errors.New("too many buckets")
+
+// Options configures a BoltDatabase opened with Open.
+// The zero value gives the same behavior as NewBoltDatabase.
+type Options struct {
+	// MaxBuckets caps the number of user buckets. Writes that would create a new
+	// bucket beyond the cap fail with ErrTooManyBuckets, while writes to existing
+	// buckets still succeed. Reserved metadata buckets are not counted. Zero means unlimited.
+	MaxBuckets int
+
+	// MaxBatchSize is the largest number of writes bolt coalesces into one transaction
+	// for Set, Delete and the other batched writes. Zero keeps bolt's default.
+	MaxBatchSize int
+
+	// MaxBatchDelay is how long bolt waits for more writes before committing a batch.
+	// Zero keeps bolt's default.
+	MaxBatchDelay time.Duration
+
+	// AutoReopenOnError makes Get, Set and Delete close and reopen the database file once
+	// and retry when they fail with a recoverable error, such as an mmap failure.
+	// A database closed with Close is never reopened.
+	AutoReopenOnError bool
+
+	// MmapFlags are extra flags passed to mmap when bolt maps the file, such as
+	// syscall.MAP_POPULATE to prefault pages for faster warm reads. They take effect on
+	// Unix systems only and are ignored on Windows. syscall.MAP_POPULATE is only defined
+	// on Linux, so code using it must be guarded by a build constraint. Zero uses no
+	// extra flags.
+	MmapFlags int
+
+	// AllowNegativeBalance lets Transfer take a balance below zero. By default a
+	// transfer that would do so fails with ErrInsufficientBalance.
+	AllowNegativeBalance bool
+}
+
+// Open opens a Bolt database at the specified path with the given options.
+// The database file will be created with read/write permissions (0600).
+//
+// Parameters:
+//   - dbPath: The file path where the database should be created/opened
+//   - opts: The database options, or nil for defaults
+//
+// Returns:
+//   - *BoltDatabase: A new database instance
+//   - error: Any error that occurred while opening the database
+func Open(dbPath string, opts *Options) (*BoltDatabase, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	var boltOpts *bolt.Options
+	if opts.MmapFlags != 0 {
+		boltOpts = &bolt.Options{MmapFlags: opts.MmapFlags}
+	}
+	db, err := NewBoltDatabaseWithOptions(dbPath, 0600, boltOpts)
+	if err != nil {
+		return nil, err
+	}
+	db.opts = *opts
+	db.applyOptions()
+	return db, nil
+}
+
+// applyOptions applies the options that live on the underlying *bolt.DB. It is called
+// after every open, including the reopens done by CompactInPlace and AutoReopenOnError.
+func (b *BoltDatabase) applyOptions() {
+	if b.opts.MaxBatchSize > 0 {
+		b.db.Load().MaxBatchSize = b.opts.MaxBatchSize
+	}
+	if b.opts.MaxBatchDelay > 0 {
+		b.db.Load().MaxBatchDelay = b.opts.MaxBatchDelay
+	}
+}
+
+// createBucketIfNotExists returns the named bucket, creating it if it doesn't exist
+// and the MaxBuckets limit allows it.
+func (b *BoltDatabase) createBucketIfNotExists(tx *bolt.Tx, bucketName string) (*bolt.Bucket, error) {
+	if bucket := tx.Bucket([]byte(bucketName)); bucket != nil {
+		return bucket, nil
+	}
+
+	if b.opts.MaxBuckets > 0 {
+		count := 0
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if !b.isReserved(string(name)) {
+				count++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if count >= b.opts.MaxBuckets {
+			return nil, fmt.Errorf("%w: limit of %d reached", ErrTooManyBuckets, b.opts.MaxBuckets)
+		}
+	}
+	return tx.CreateBucket([]byte(bucketName))
+}
@@ -0,0 +1,28 @@
+package boltdb
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestMmapFlagsPopulateKeepsReadsWorking(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mmap.db")
+	db, err := Open(path, &Options{MmapFlags: syscall.MAP_POPULATE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, db, "warm", "k", []byte("v"))
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(path, &Options{MmapFlags: syscall.MAP_POPULATE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := bucketContents(t, db, "warm"); got["k"] != "v" {
+		t.Fatalf("contents = %v, want k=v", got)
+	}
+}
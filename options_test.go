@@ -0,0 +1,53 @@
+package boltdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxBucketsCapsBucketCreation(t *testing.T) {
+	db := newTestDB(t, &Options{MaxBuckets: 2})
+	mustSet(t, db, "a", "k", []byte("v"))
+	mustSet(t, db, "b", "k", []byte("v"))
+
+	if err := db.Set("c", "k", []byte("v")); !errors.Is(err, ErrTooManyBuckets) {
+		t.Fatalf("third bucket: got %v, want ErrTooManyBuckets", err)
+	}
+	mustSet(t, db, "a", "k2", []byte("v"))
+
+	if got := len(bucketNames(t, db)); got != 2 {
+		t.Fatalf("got %d buckets, want 2", got)
+	}
+}
+
+func TestBatchOptionsAreAppliedAndCoalesceWrites(t *testing.T) {
+	db := newTestDB(t, &Options{MaxBatchSize: 500, MaxBatchDelay: 20 * time.Millisecond})
+	if db.db.Load().MaxBatchSize != 500 || db.db.Load().MaxBatchDelay != 20*time.Millisecond {
+		t.Fatalf("bolt batch settings = %d, %v", db.db.Load().MaxBatchSize, db.db.Load().MaxBatchDelay)
+	}
+	mustSet(t, db, "data", "warm", []byte("v"))
+
+	const writers = 50
+	before := db.db.Load().Stats()
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := db.Set("data", fmt.Sprintf("k%02d", i), []byte("v")); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	after := db.db.Load().Stats()
+
+	// Every commit writes at least a meta page, so separate transactions would need
+	// at least one page write per Set.
+	if writes := after.Sub(&before).TxStats.Write; writes >= writers {
+		t.Fatalf("%d concurrent Sets caused %d page writes; they were not coalesced", writers, writes)
+	}
+}
@@ -0,0 +1,139 @@
+package boltdb
+
+import (
+	"encoding/binary"
+
+	"github.com/boltdb/bolt"
+)
+
+// orderBucketPrefix prefixes the reserved buckets tracking insertion order.
+// The order of bucket "events" lives in "__order__:events", which holds a nested "seq"
+// bucket mapping big-endian sequence numbers to keys and a "key" bucket mapping keys back.
+const orderBucketPrefix = "__order__:"
+
+var (
+	orderSeqBucket = []byte("seq")
+	orderKeyBucket = []byte("key")
+)
+
+// EnableInsertionOrder makes Set, Delete, batches and the other write methods track the
+// order in which keys of the named bucket are first inserted, so ListInsertionOrder can return them in that order.
+// Overwriting a key keeps its original position, and deleting it removes the position.
+// Like codecs, the setting is kept in memory and must be enabled again after reopening.
+// Writes made directly through the bolt bucket given by Update are not tracked.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to track
+func (b *BoltDatabase) EnableInsertionOrder(bucketName string) {
+	b.orderedBuckets.Store(bucketName, true)
+}
+
+// ListInsertionOrder returns the entries of the specified bucket in the order their keys
+// were first inserted. Keys not tracked, such as those written before EnableInsertionOrder,
+// follow the tracked ones in key order.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to list
+//
+// Returns:
+//   - []KeyValue: The entries in insertion order, with values copied out of the transaction
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) ListInsertionOrder(bucketName string) ([]KeyValue, error) {
+	result := make([]KeyValue, 0)
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+
+		var keyIndex *bolt.Bucket
+		if order := tx.Bucket([]byte(orderBucketPrefix + bucketName)); order != nil {
+			keyIndex = order.Bucket(orderKeyBucket)
+			if seqIndex := order.Bucket(orderSeqBucket); seqIndex != nil {
+				err := seqIndex.ForEach(func(_, key []byte) error {
+					return b.appendKeyValue(tx, bucket, bucketName, key, &result)
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			if (keyIndex != nil && keyIndex.Get(k) != nil) || (v == nil && bucket.Bucket(k) != nil) {
+				return nil
+			}
+			return b.appendKeyValue(tx, bucket, bucketName, k, &result)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// appendKeyValue appends the entry stored under key, if any, to result.
+func (b *BoltDatabase) appendKeyValue(tx *bolt.Tx, bucket *bolt.Bucket, bucketName string, key []byte, result *[]KeyValue) error {
+	if !hasKey(bucket, key) {
+		return nil
+	}
+	value, err := b.readValue(tx, bucketName, string(key), bucket.Get(key))
+	if err != nil {
+		return err
+	}
+	*result = append(*result, KeyValue{Key: string(key), Value: append([]byte{}, value...)})
+	return nil
+}
+
+// trackInsert records key in the insertion order of bucketName if it is tracked and the
+// key is not already present.
+func (b *BoltDatabase) trackInsert(tx *bolt.Tx, bucketName, key string) error {
+	if _, ok := b.orderedBuckets.Load(bucketName); !ok {
+		return nil
+	}
+	order, err := tx.CreateBucketIfNotExists([]byte(orderBucketPrefix + bucketName))
+	if err != nil {
+		return err
+	}
+	keyIndex, err := order.CreateBucketIfNotExists(orderKeyBucket)
+	if err != nil {
+		return err
+	}
+	if keyIndex.Get([]byte(key)) != nil {
+		return nil
+	}
+	seqIndex, err := order.CreateBucketIfNotExists(orderSeqBucket)
+	if err != nil {
+		return err
+	}
+	n, err := order.NextSequence()
+	if err != nil {
+		return err
+	}
+	seq := binary.BigEndian.AppendUint64(nil, n)
+	if err := seqIndex.Put(seq, []byte(key)); err != nil {
+		return err
+	}
+	return keyIndex.Put([]byte(key), seq)
+}
+
+// trackDelete removes key from the insertion order of bucketName, if recorded.
+func (b *BoltDatabase) trackDelete(tx *bolt.Tx, bucketName, key string) error {
+	order := tx.Bucket([]byte(orderBucketPrefix + bucketName))
+	if order == nil {
+		return nil
+	}
+	keyIndex, seqIndex := order.Bucket(orderKeyBucket), order.Bucket(orderSeqBucket)
+	if keyIndex == nil || seqIndex == nil {
+		return nil
+	}
+	seq := keyIndex.Get([]byte(key))
+	if seq == nil {
+		return nil
+	}
+	seq = append([]byte(nil), seq...)
+	if err := seqIndex.Delete(seq); err != nil {
+		return err
+	}
+	return keyIndex.Delete([]byte(key))
+}
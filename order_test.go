@@ -0,0 +1,74 @@
+package boltdb
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// orderedKeys returns the keys of bucketName in insertion order.
+func orderedKeys(t *testing.T, db *BoltDatabase, bucketName string) string {
+	t.Helper()
+	entries, err := db.ListInsertionOrder(bucketName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key + "=" + string(e.Value)
+	}
+	return strings.Join(keys, ",")
+}
+
+func TestListInsertionOrderKeepsFirstInsertPosition(t *testing.T) {
+	db := newTestDB(t, nil)
+	db.EnableInsertionOrder("events")
+
+	for _, k := range []string{"c", "a", "b"} {
+		mustSet(t, db, "events", k, []byte("1"))
+	}
+	if got, want := orderedKeys(t, db, "events"), "c=1,a=1,b=1"; got != want {
+		t.Fatalf("order = %s, want %s", got, want)
+	}
+
+	mustSet(t, db, "events", "c", []byte("2"))
+	if err := db.Delete("events", "a"); err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, db, "events", "a", []byte("3"))
+	if got, want := orderedKeys(t, db, "events"), "c=2,b=1,a=3"; got != want {
+		t.Fatalf("order after overwrite and reinsert = %s, want %s", got, want)
+	}
+}
+
+func TestInsertionOrderTracksEveryWritePath(t *testing.T) {
+	db := newTestDB(t, nil)
+	db.EnableInsertionOrder("b")
+
+	mustSet(t, db, "b", "c", []byte("1"))
+	batch := db.NewBatch()
+	if err := batch.AddSet("b", "a", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Modify("b", "b", func([]byte) ([]byte, error) { return []byte("3"), nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := db.GetOrInit("b", "0", []byte("4")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := db.ListInsertionOrder("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	if want := []string{"c", "a", "b", "0"}; !slices.Equal(keys, want) {
+		t.Fatalf("insertion order = %v, want %v", keys, want)
+	}
+}
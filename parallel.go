@@ -0,0 +1,75 @@
+package boltdb
+
+import (
+	"context"
+
+	"github.com/boltdb/bolt"
+	"golang.org/x/sync/errgroup"
+)
+
+// ListParallel reads every entry of the specified bucket in a single read transaction
+// and hands them to a bounded pool of workers running process.
+// Keys and values are copied before being dispatched, so process may retain them.
+// Entries are processed in no particular order. The first error returned by process
+// stops reading and is returned once all workers have finished; ErrStopIteration stops
+// it without an error. A panic in process is returned as an error wrapping
+// ErrCallbackPanic.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to read
+//   - workers: The number of concurrent workers; values below 1 mean one worker
+//   - process: A function called once for each key-value pair
+//
+// Returns:
+//   - error: The first error from process or the transaction
+func (b *BoltDatabase) ListParallel(bucketName string, workers int, process func(k, v []byte) error) error {
+	workers = max(workers, 1)
+
+	type entry struct {
+		key   []byte
+		value []byte
+	}
+	entries := make(chan entry, workers)
+	wg, ctx := errgroup.WithContext(context.Background())
+
+	for range workers {
+		wg.Go(func() (err error) {
+			defer recoverCallback(&err)
+			for e := range entries {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err := process(e.key, e.value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	wg.Go(func() error {
+		defer close(entries)
+		return b.db.Load().View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket([]byte(bucketName))
+			if bucket == nil {
+				return nil
+			}
+			c := bucket.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				value, err := b.entryValue(tx, bucketName, k, v)
+				if err != nil {
+					return err
+				}
+				e := entry{key: append([]byte(nil), k...), value: append([]byte(nil), value...)}
+				select {
+				case entries <- e:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			return nil
+		})
+	})
+
+	return stopIteration(wg.Wait())
+}
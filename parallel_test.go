@@ -0,0 +1,78 @@
+package boltdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestListParallelProcessesEachEntryOnce(t *testing.T) {
+	db := newTestDB(t, nil)
+	const n = 500
+	err := db.db.Load().Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("data"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := bucket.Put([]byte(fmt.Sprintf("k%04d", i)), []byte(fmt.Sprint(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]string)
+	err = db.ListParallel("data", 4, func(k, v []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, dup := seen[string(k)]; dup {
+			return fmt.Errorf("%s processed twice", k)
+		}
+		seen[string(k)] = string(v)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != n {
+		t.Fatalf("processed %d entries, want %d", len(seen), n)
+	}
+	for i := 0; i < n; i++ {
+		if v := seen[fmt.Sprintf("k%04d", i)]; v != fmt.Sprint(i) {
+			t.Fatalf("k%04d = %q, want %d", i, v, i)
+		}
+	}
+
+	errStop := errors.New("stop")
+	err = db.ListParallel("data", 4, func(k, v []byte) error {
+		if string(k) == "k0100" {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("got %v, want errStop", err)
+	}
+}
+
+func TestListParallelRecoversWorkerPanic(t *testing.T) {
+	db := newTestDB(t, nil)
+	for _, key := range []string{"k1", "k2", "k3", "k4"} {
+		mustSet(t, db, "b", key, []byte("v"))
+	}
+
+	err := db.ListParallel("b", 2, func(k, v []byte) error {
+		panic("boom")
+	})
+	if !errors.Is(err, ErrCallbackPanic) {
+		t.Fatalf("ListParallel = %v, want ErrCallbackPanic", err)
+	}
+}
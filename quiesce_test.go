@@ -0,0 +1,39 @@
+package boltdb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuiesceBlocksWritesUntilResume(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "data", "k", []byte("old"))
+
+	db.Quiesce()
+	done := make(chan error, 1)
+	go func() { done <- db.Set("data", "k", []byte("new")) }()
+
+	select {
+	case err := <-done:
+		db.Resume()
+		t.Fatalf("Set completed while quiesced: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if v, err := db.Get("data", "k"); err != nil || string(v) != "old" {
+		db.Resume()
+		t.Fatalf("read while quiesced = %q, %v", v, err)
+	}
+
+	db.Resume()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Set did not complete after Resume")
+	}
+	if v, _ := db.Get("data", "k"); string(v) != "new" {
+		t.Fatalf("got %q, want new", v)
+	}
+}
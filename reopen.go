@@ -0,0 +1,60 @@
+package boltdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+// withReopen runs op and, if Options.AutoReopenOnError is set and op fails with a
+// recoverable error, reopens the database file once and runs op again.
+func (b *BoltDatabase) withReopen(op func() error) error {
+	failed := b.db.Load()
+	err := op()
+	if err == nil || !b.opts.AutoReopenOnError || !b.isRecoverable(err) {
+		return err
+	}
+	if reopenErr := b.reopen(failed); reopenErr != nil {
+		return fmt.Errorf("reopen after %v: %w", err, reopenErr)
+	}
+	return op()
+}
+
+// reopen closes and reopens the database file, unless another caller already replaced
+// the handle that failed. Writes are held off by the write gate during the swap; reads
+// that race with it fail with bolt.ErrDatabaseNotOpen, which is itself recoverable.
+// A database closed by Close is never reopened.
+func (b *BoltDatabase) reopen(failed *bolt.DB) error {
+	b.writeGate.Lock()
+	defer b.writeGate.Unlock()
+	b.swapLck.Lock()
+	defer b.swapLck.Unlock()
+
+	if b.closed.Load() {
+		return bolt.ErrDatabaseNotOpen
+	}
+	if b.db.Load() != failed {
+		return nil
+	}
+	if err := failed.Close(); err != nil && !errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		return err
+	}
+	db, err := bolt.Open(b.dbPath, b.mode, b.boltOpts)
+	if err != nil {
+		return err
+	}
+	b.db.Store(db)
+	b.applyOptions()
+	return nil
+}
+
+// isRecoverable reports whether err is cleared by reopening the database file.
+// bolt.ErrDatabaseNotOpen only counts while the database has not been closed by Close.
+func (b *BoltDatabase) isRecoverable(err error) bool {
+	if errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		return !b.closed.Load()
+	}
+	return errors.Is(err, ErrTransient) || strings.HasPrefix(err.Error(), "mmap")
+}
@@ -0,0 +1,99 @@
+package boltdb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestWithReopenRetriesAfterInjectedFault(t *testing.T) {
+	db := newTestDB(t, &Options{AutoReopenOnError: true})
+	mustSet(t, db, "b", "k", []byte("v"))
+
+	before := db.db.Load()
+	calls := 0
+	var got []byte
+	err := db.withReopen(func() error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("injected: %w", ErrTransient)
+		}
+		var err error
+		got, err = db.Get("b", "k")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("withReopen: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("op ran %d times, want 2", calls)
+	}
+	if db.db.Load() == before {
+		t.Fatal("database was not reopened")
+	}
+	if string(got) != "v" {
+		t.Fatalf("got %q after reopen, want %q", got, "v")
+	}
+}
+
+func TestWithReopenPassesThroughOtherErrors(t *testing.T) {
+	db := newTestDB(t, &Options{AutoReopenOnError: true})
+
+	before := db.db.Load()
+	injected := errors.New("not recoverable")
+	calls := 0
+	err := db.withReopen(func() error {
+		calls++
+		return injected
+	})
+	if !errors.Is(err, injected) || calls != 1 {
+		t.Fatalf("got %v after %d calls, want the injected error after 1", err, calls)
+	}
+	if db.db.Load() != before {
+		t.Fatal("database was reopened for a non-recoverable error")
+	}
+}
+
+func TestAutoReopenRecoversLostHandle(t *testing.T) {
+	db := newTestDB(t, &Options{AutoReopenOnError: true})
+	mustSet(t, db, "b", "k", []byte("v"))
+
+	// Closing the bolt handle underneath simulates a failure the package did not cause.
+	if err := db.db.Load().Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.Get("b", "k")
+	if err != nil || string(got) != "v" {
+		t.Fatalf("Get = %q, %v; want %q, nil", got, err, "v")
+	}
+	if err := db.Set("b", "k2", []byte("v2")); err != nil {
+		t.Fatalf("Set after reopen: %v", err)
+	}
+}
+
+func TestAutoReopenKeepsClosedDatabaseClosed(t *testing.T) {
+	db := newTestDB(t, &Options{AutoReopenOnError: true})
+	mustSet(t, db, "b", "k", []byte("v"))
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get("b", "k"); !errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		t.Fatalf("Get after Close = %v, want bolt.ErrDatabaseNotOpen", err)
+	}
+	if err := db.Set("b", "k", []byte("v")); !errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		t.Fatalf("Set after Close = %v, want bolt.ErrDatabaseNotOpen", err)
+	}
+}
+
+func TestWithoutAutoReopenErrorsPassThrough(t *testing.T) {
+	db := newTestDB(t, nil)
+	if err := db.db.Load().Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get("data", "k"); !errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		t.Fatalf("Get = %v, want ErrDatabaseNotOpen", err)
+	}
+}
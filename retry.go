@@ -0,0 +1,45 @@
+package boltdb
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// RETRY_BACKOFF is the base delay between attempts of ViewWithRetry; the delay grows
+// linearly with each attempt.
+const RETRY_BACKOFF = 10 * time.Millisecond
+
+// ErrTransient marks an error as transient so that ViewWithRetry retries it.
+// Callbacks can wrap it to request a retry of the whole read.
+var ErrTransient = errors.New("transient error")
+
+// ViewWithRetry runs fn in a read transaction, retrying when the read fails with a
+// transient error: an mmap failure or an error wrapping ErrTransient. Any other error,
+// including ordinary errors returned by fn, is returned immediately without retrying.
+// bolt.ErrTimeout is not retried, since bolt only returns it when opening a file whose
+// lock is held, never from a read transaction.
+//
+// Parameters:
+//   - fn: A function that will be called with the read transaction
+//   - attempts: The maximum number of attempts; values below 1 mean a single attempt
+//
+// Returns:
+//   - error: The last error if every attempt failed, or nil on success
+func (b *BoltDatabase) ViewWithRetry(fn func(tx *bolt.Tx) error, attempts int) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = b.db.Load().View(fn)
+		if err == nil || !isTransient(err) || attempt >= attempts {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * RETRY_BACKOFF)
+	}
+}
+
+// isTransient reports whether err is worth retrying.
+func isTransient(err error) bool {
+	return errors.Is(err, ErrTransient) || strings.HasPrefix(err.Error(), "mmap")
+}
@@ -0,0 +1,57 @@
+package boltdb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestViewWithRetryRetriesTransientErrors(t *testing.T) {
+	db := newTestDB(t, nil)
+
+	calls := 0
+	err := db.ViewWithRetry(func(tx *bolt.Tx) error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("remap in progress: %w", ErrTransient)
+		}
+		return nil
+	}, 5)
+	if err != nil || calls != 3 {
+		t.Fatalf("got %v after %d calls, want success after 3", err, calls)
+	}
+}
+
+func TestViewWithRetryStopsOnPermanentErrors(t *testing.T) {
+	db := newTestDB(t, nil)
+	errPermanent := errors.New("permanent")
+
+	calls := 0
+	err := db.ViewWithRetry(func(tx *bolt.Tx) error {
+		calls++
+		return errPermanent
+	}, 5)
+	if !errors.Is(err, errPermanent) || calls != 1 {
+		t.Fatalf("got %v after %d calls, want errPermanent after 1", err, calls)
+	}
+
+	calls = 0
+	err = db.ViewWithRetry(func(tx *bolt.Tx) error {
+		calls++
+		return ErrTransient
+	}, 2)
+	if !errors.Is(err, ErrTransient) || calls != 2 {
+		t.Fatalf("got %v after %d calls, want ErrTransient after 2", err, calls)
+	}
+
+	calls = 0
+	err = db.ViewWithRetry(func(tx *bolt.Tx) error {
+		calls++
+		return bolt.ErrTimeout
+	}, 5)
+	if !errors.Is(err, bolt.ErrTimeout) || calls != 1 {
+		t.Fatalf("got %v after %d calls, want bolt.ErrTimeout after 1", err, calls)
+	}
+}
@@ -0,0 +1,312 @@
+package boltdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// ScanPrefix iterates over the entries of the specified bucket whose keys start with prefix.
+// A cursor seeks straight to the prefix and iteration stops at the first key outside it,
+// so only the matching range of the bucket is visited. Entries are visited in key order.
+// Returning ErrStopIteration from fn stops the iteration without an error.
+// A panic in fn is returned as an error wrapping ErrCallbackPanic.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to scan
+//   - prefix: The key prefix to match, or empty for every key
+//   - fn: A function that will be called for each matching key-value pair
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) ScanPrefix(bucketName, prefix string, fn func(key, value []byte) error) (err error) {
+	defer recoverCallback(&err)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		p := []byte(prefix)
+		c := bucket.Cursor()
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			if err := fn(k, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return stopIteration(err)
+}
+
+// Range iterates over the entries of the specified bucket with keys in [start, end).
+// Keys are ordered byte-wise, as bolt stores them, so the window is lexicographic: this
+// matches chronological order for fixed-width keys such as RFC3339 timestamps in a single
+// time zone, but not for numbers of varying width. An empty start means from the first key
+// and an empty end means through the last key. A cursor seeks straight to start, so keys
+// outside the window are not visited. Returning ErrStopIteration from fn stops the
+// iteration without an error. A panic in fn is returned as an error wrapping ErrCallbackPanic.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to iterate over
+//   - start: The first key of the window, inclusive
+//   - end: The key ending the window, exclusive
+//   - fn: A function that will be called for each key-value pair in the window
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) Range(bucketName, start, end string, fn func(key, value []byte) error) (err error) {
+	defer recoverCallback(&err)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		k, v := c.First()
+		if start != "" {
+			k, v = c.Seek([]byte(start))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if end != "" && bytes.Compare(k, []byte(end)) >= 0 {
+				break
+			}
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			if err := fn(k, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return stopIteration(err)
+}
+
+// ForEachReverse iterates over all key-value pairs in the specified bucket from the last
+// key to the first, which suits newest-first listings of time-ordered keys.
+// Returning ErrStopIteration from fn stops the iteration without an error.
+// A panic in fn is returned as an error wrapping ErrCallbackPanic.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to iterate over
+//   - fn: A function that will be called for each key-value pair
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) ForEachReverse(bucketName string, fn func(key, value []byte) error) (err error) {
+	defer recoverCallback(&err)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			if err := fn(k, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return stopIteration(err)
+}
+
+// RangeSeq iterates over the entries of the specified bucket whose keys are sequence numbers
+// in [fromSeq, toSeq], inclusive like the ranges returned by ReserveIDs. Keys are expected to
+// be 8-byte big-endian uint64 values, whose byte order matches numeric order, so a cursor
+// seeks straight to fromSeq. Keys of any other length are skipped.
+// Returning ErrStopIteration from fn stops the iteration without an error.
+// A panic in fn is returned as an error wrapping ErrCallbackPanic.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to iterate over
+//   - fromSeq: The first sequence number, inclusive
+//   - toSeq: The last sequence number, inclusive
+//   - fn: A function that will be called for each sequence number and value, in order
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) RangeSeq(bucketName string, fromSeq, toSeq uint64, fn func(seq uint64, value []byte) error) (err error) {
+	if toSeq < fromSeq {
+		return nil
+	}
+	defer recoverCallback(&err)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Seek(binary.BigEndian.AppendUint64(nil, fromSeq)); k != nil; k, v = c.Next() {
+			if len(k) != 8 {
+				continue
+			}
+			seq := binary.BigEndian.Uint64(k)
+			if seq > toSeq {
+				break
+			}
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			if err := fn(seq, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return stopIteration(err)
+}
+
+// Page returns up to limit entries of the specified bucket in key order, starting strictly
+// after afterKey, so a large bucket can be listed without loading it into memory at once.
+// Pass the returned nextKey as afterKey to fetch the following page. Each page is read in
+// its own transaction, so writes between calls are reflected in later pages.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to list
+//   - afterKey: The key after which the page starts, or empty to start from the beginning
+//   - limit: The maximum number of entries to return, at least 1
+//
+// Returns:
+//   - []string: The keys of the page, in order
+//   - [][]byte: The values of the page, copied out of the transaction
+//   - string: The key to pass as afterKey for the next page, or empty when exhausted
+//   - error: An error if limit is below 1 or the operation fails
+func (b *BoltDatabase) Page(bucketName string, afterKey string, limit int) (keys []string, values [][]byte, nextKey string, err error) {
+	if limit < 1 {
+		return nil, nil, "", errors.New("limit must be at least 1")
+	}
+	keys = make([]string, 0, limit)
+	values = make([][]byte, 0, limit)
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		k, v := c.First()
+		if afterKey != "" {
+			k, v = c.Seek([]byte(afterKey))
+			if k != nil && string(k) == afterKey {
+				k, v = c.Next()
+			}
+		}
+		for ; k != nil; k, v = c.Next() {
+			if len(keys) == limit {
+				nextKey = keys[len(keys)-1]
+				break
+			}
+			value, err := b.entryValue(tx, bucketName, k, v)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, string(k))
+			values = append(values, append([]byte(nil), value...))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return keys, values, nextKey, nil
+}
+
+// KeyPercentile returns the key at fraction p of the specified bucket's key order, such as
+// the median key for p = 0.5, to help choose shard boundaries. A cursor counts the keys and
+// then walks to index floor(p*count), clamped to the last key. Nested buckets are skipped.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to inspect
+//   - p: The fraction of the key order, in [0, 1]
+//
+// Returns:
+//   - string: The key at the percentile, or empty if the bucket is missing or empty
+//   - error: An error if p is outside [0, 1] or the operation fails
+func (b *BoltDatabase) KeyPercentile(bucketName string, p float64) (string, error) {
+	if !(p >= 0 && p <= 1) {
+		return "", fmt.Errorf("percentile %v is outside [0, 1]", p)
+	}
+	var result string
+	err := b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		count := 0
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v != nil {
+				count++
+			}
+		}
+		if count == 0 {
+			return nil
+		}
+		target := min(int(p*float64(count)), count-1)
+
+		i := 0
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+			if i == target {
+				result = string(k)
+				break
+			}
+			i++
+		}
+		return nil
+	})
+	return result, err
+}
+
+// SequenceBounds returns the smallest and largest sequence keys of the specified bucket,
+// such as a log written with ReserveIDs, to help compute retention windows. Keys are
+// expected to be 8-byte big-endian uint64 values, as with RangeSeq.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to inspect
+//
+// Returns:
+//   - uint64: The first sequence number
+//   - uint64: The last sequence number
+//   - bool: False if the bucket is missing or empty
+//   - error: An error if the first or last key is not a sequence key, or the operation fails
+func (b *BoltDatabase) SequenceBounds(bucketName string) (min, max uint64, ok bool, err error) {
+	err = b.db.Load().View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		first, _ := c.First()
+		last, _ := c.Last()
+		if first == nil {
+			return nil
+		}
+		for _, k := range [][]byte{first, last} {
+			if len(k) != 8 {
+				return fmt.Errorf("key %x is not an 8-byte sequence key", k)
+			}
+		}
+		min, max, ok = binary.BigEndian.Uint64(first), binary.BigEndian.Uint64(last), true
+		return nil
+	})
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return min, max, ok, nil
+}
@@ -0,0 +1,339 @@
+package boltdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// seedKeys stores each key with its own name as the value.
+func seedKeys(t *testing.T, db *BoltDatabase, bucketName string, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		mustSet(t, db, bucketName, k, []byte(k))
+	}
+}
+
+func TestScanPrefixBoundaries(t *testing.T) {
+	db := newTestDB(t, nil)
+	seedKeys(t, db, "data", "a", "user:1", "user:2", "user:20", "userx", "z")
+	w := NewBoltDBWrapper(db, "data")
+
+	cases := map[string]string{
+		"user:":  "user:1,user:2,user:20",
+		"user:2": "user:2,user:20",
+		"":       "a,user:1,user:2,user:20,userx,z",
+		"b":      "",
+		"zz":     "",
+	}
+	for prefix, want := range cases {
+		var got []string
+		err := w.ScanPrefix(prefix, func(k, v []byte) error {
+			got = append(got, string(k))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := strings.Join(got, ","); s != want {
+			t.Errorf("ScanPrefix(%q) = %q, want %q", prefix, s, want)
+		}
+	}
+
+	called := false
+	if err := db.ScanPrefix("missing", "", func(k, v []byte) error { called = true; return nil }); err != nil || called {
+		t.Fatalf("missing bucket: called=%v, err=%v", called, err)
+	}
+}
+
+func TestRangeBoundaries(t *testing.T) {
+	db := newTestDB(t, nil)
+	seedKeys(t, db, "data", "2024-01-01", "2024-01-02", "2024-01-03", "2024-02-01")
+
+	cases := []struct{ start, end, want string }{
+		{"2024-01-02", "2024-02-01", "2024-01-02,2024-01-03"},
+		{"2024-01-01", "2024-01-01", ""},
+		{"2024-01-015", "2024-01-03", "2024-01-02"},
+		{"", "2024-01-02", "2024-01-01"},
+		{"2024-01-03", "", "2024-01-03,2024-02-01"},
+		{"", "", "2024-01-01,2024-01-02,2024-01-03,2024-02-01"},
+		{"2025", "", ""},
+		{"2024-02-01", "2024-01-01", ""},
+	}
+	for _, c := range cases {
+		var got []string
+		err := db.Range("data", c.start, c.end, func(k, v []byte) error {
+			got = append(got, string(k))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := strings.Join(got, ","); s != c.want {
+			t.Errorf("Range(%q, %q) = %q, want %q", c.start, c.end, s, c.want)
+		}
+	}
+}
+
+func TestForEachReverseAndStopIteration(t *testing.T) {
+	db := newTestDB(t, nil)
+	seedKeys(t, db, "data", "a", "b", "c", "d")
+
+	var got []string
+	err := db.ForEachReverse("data", func(k, v []byte) error {
+		got = append(got, string(k))
+		return nil
+	})
+	if err != nil || strings.Join(got, ",") != "d,c,b,a" {
+		t.Fatalf("ForEachReverse = %q, %v", got, err)
+	}
+
+	got = nil
+	err = db.ForEachReverse("data", func(k, v []byte) error {
+		if string(k) == "b" {
+			return ErrStopIteration
+		}
+		got = append(got, string(k))
+		return nil
+	})
+	if err != nil || strings.Join(got, ",") != "d,c" {
+		t.Fatalf("stopped ForEachReverse = %q, %v", got, err)
+	}
+
+	got = nil
+	err = db.ForEach("data", func(k, v []byte) error {
+		got = append(got, string(k))
+		if len(got) == 2 {
+			return ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil || strings.Join(got, ",") != "a,b" {
+		t.Fatalf("stopped ForEach = %q, %v", got, err)
+	}
+
+	called := false
+	if err := db.ForEachReverse("missing", func(k, v []byte) error { called = true; return nil }); err != nil || called {
+		t.Fatalf("missing bucket: called=%v, err=%v", called, err)
+	}
+}
+
+func TestRangeSeqIteratesNumericRange(t *testing.T) {
+	db := newTestDB(t, nil)
+	for _, seq := range []uint64{1, 2, 9, 10, 255, 256, 1000, math.MaxUint64} {
+		mustSet(t, db, "log", string(binary.BigEndian.AppendUint64(nil, seq)), []byte(fmt.Sprint(seq)))
+	}
+	mustSet(t, db, "log", "not-a-seq", []byte("x"))
+
+	cases := []struct {
+		from, to uint64
+		want     string
+	}{
+		{2, 256, "2,9,10,255,256"},
+		{3, 8, ""},
+		{0, 1, "1"},
+		{1000, math.MaxUint64, "1000,18446744073709551615"},
+		{10, 9, ""},
+	}
+	for _, c := range cases {
+		var got []string
+		err := db.RangeSeq("log", c.from, c.to, func(seq uint64, value []byte) error {
+			if string(value) != fmt.Sprint(seq) {
+				t.Errorf("seq %d has value %q", seq, value)
+			}
+			got = append(got, fmt.Sprint(seq))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := strings.Join(got, ","); s != c.want {
+			t.Errorf("RangeSeq(%d, %d) = %q, want %q", c.from, c.to, s, c.want)
+		}
+	}
+}
+
+func TestPageWalksBucketInOrder(t *testing.T) {
+	db := newTestDB(t, nil)
+	seedKeys(t, db, "data", "a", "b", "c", "d", "e", "f")
+
+	var pages []string
+	after := ""
+	for {
+		keys, values, next, err := db.Page("data", after, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range keys {
+			if string(values[i]) != keys[i] {
+				t.Fatalf("%s = %q", keys[i], values[i])
+			}
+		}
+		pages = append(pages, strings.Join(keys, ""))
+		if next == "" {
+			break
+		}
+		after = next
+	}
+	if got := strings.Join(pages, "|"); got != "abcd|ef" {
+		t.Fatalf("pages = %q, want abcd|ef", got)
+	}
+
+	if keys, _, next, err := db.Page("data", "b5", 2); err != nil || strings.Join(keys, "") != "cd" || next != "d" {
+		t.Fatalf("Page after absent key = %v, %q, %v", keys, next, err)
+	}
+	if keys, _, next, err := db.Page("data", "f", 2); err != nil || len(keys) != 0 || next != "" {
+		t.Fatalf("Page after last key = %v, %q, %v", keys, next, err)
+	}
+	if _, _, _, err := db.Page("data", "", 0); err == nil {
+		t.Fatal("expected an error for a zero limit")
+	}
+}
+
+func TestKeyPercentile(t *testing.T) {
+	db := newTestDB(t, nil)
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		mustSet(t, db, "shards", k, []byte("v"))
+	}
+	err := db.db.Load().Update(func(tx *bolt.Tx) error {
+		nested, err := tx.Bucket([]byte("shards")).CreateBucket([]byte("b2"))
+		if err != nil {
+			return err
+		}
+		for _, k := range []string{"x", "y", "z"} {
+			if err := nested.Put([]byte(k), []byte("v")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		p    float64
+		want string
+	}{{0, "a"}, {0.25, "c"}, {0.5, "e"}, {0.75, "g"}, {1, "h"}} {
+		got, err := db.KeyPercentile("shards", tc.p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("KeyPercentile(%v) = %q, want %q", tc.p, got, tc.want)
+		}
+	}
+
+	for _, p := range []float64{-0.1, 1.5, math.NaN()} {
+		if _, err := db.KeyPercentile("shards", p); err == nil {
+			t.Errorf("KeyPercentile(%v) succeeded, want an error", p)
+		}
+	}
+	if got, err := db.KeyPercentile("missing", 0.5); err != nil || got != "" {
+		t.Fatalf("missing bucket = %q, %v", got, err)
+	}
+}
+
+func TestSequenceBounds(t *testing.T) {
+	db := newTestDB(t, nil)
+	if _, _, ok, err := db.SequenceBounds("log"); err != nil || ok {
+		t.Fatalf("missing bucket: ok=%v, err=%v", ok, err)
+	}
+
+	for _, n := range []uint64{42, 7, 300} {
+		mustSet(t, db, "log", string(binary.BigEndian.AppendUint64(nil, n)), []byte("entry"))
+	}
+	min, max, ok, err := db.SequenceBounds("log")
+	if err != nil || !ok || min != 7 || max != 300 {
+		t.Fatalf("SequenceBounds = %d, %d, %v, %v; want 7, 300, true", min, max, ok, err)
+	}
+
+	mustSet(t, db, "log", "zzz", []byte("not a sequence"))
+	if _, _, _, err := db.SequenceBounds("log"); err == nil {
+		t.Fatal("SequenceBounds accepted a non-sequence last key")
+	}
+}
+
+func TestErrStopIterationStopsEveryIterator(t *testing.T) {
+	db := newTestDB(t, nil)
+	for _, key := range []string{"k1", "k2", "k3"} {
+		mustSet(t, db, "b", key, []byte("v"))
+		mustSet(t, db, "other", key, []byte("v"))
+	}
+	for seq := uint64(1); seq <= 3; seq++ {
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		mustSet(t, db, "seq", string(key), []byte("v"))
+	}
+	dict, err := NewKeyDictWrapper(db, "dict", ":")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a:1", "a:2", "b:1"} {
+		if err := dict.Set(key, []byte("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		run  func(stop func() error) error
+	}{
+		{"ForEach", func(stop func() error) error {
+			return db.ForEach("b", func(k, v []byte) error { return stop() })
+		}},
+		{"ForEachLive", func(stop func() error) error {
+			return db.ForEachLive("b", func(k, v []byte) error { return stop() })
+		}},
+		{"ForEachValue", func(stop func() error) error {
+			return db.ForEachValue("b", func(v []byte) error { return stop() })
+		}},
+		{"ForEachReverse", func(stop func() error) error {
+			return db.ForEachReverse("b", func(k, v []byte) error { return stop() })
+		}},
+		{"ScanPrefix", func(stop func() error) error {
+			return db.ScanPrefix("b", "k", func(k, v []byte) error { return stop() })
+		}},
+		{"Range", func(stop func() error) error {
+			return db.Range("b", "k1", "k9", func(k, v []byte) error { return stop() })
+		}},
+		{"RangeSeq", func(stop func() error) error {
+			return db.RangeSeq("seq", 1, 3, func(seq uint64, v []byte) error { return stop() })
+		}},
+		{"ScanKeyParts", func(stop func() error) error {
+			return db.ScanKeyParts("b", func(parts []string, v []byte) error { return stop() })
+		}},
+		{"PriorityMerge", func(stop func() error) error {
+			return db.PriorityMerge([]string{"b", "other"}, func(k string, v []byte, from string) error { return stop() })
+		}},
+		{"ListParallel", func(stop func() error) error {
+			return db.ListParallel("b", 1, func(k, v []byte) error { return stop() })
+		}},
+		{"KeyDictWrapper.ForEach", func(stop func() error) error {
+			return dict.ForEach(func(k, v []byte) error { return stop() })
+		}},
+		{"BoltDBWrapper.ForEach", func(stop func() error) error {
+			return NewBoltDBWrapper(db, "b").ForEach(func(k, v []byte) error { return stop() })
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := tt.run(func() error {
+				calls++
+				return ErrStopIteration
+			})
+			if err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			if calls != 1 {
+				t.Fatalf("callback ran %d times after ErrStopIteration, want 1", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package boltdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// CloseTimeoutError is returned by CloseAllContext when some databases did not
+// finish closing before the context was done.
+type CloseTimeoutError struct {
+	Pending []string // Names of the databases still closing, sorted
+	Err     error    // The context error
+}
+
+// Error implements the error interface.
+func (e *CloseTimeoutError) Error() string {
+	return fmt.Sprintf("databases did not close in time: %s: %v", strings.Join(e.Pending, ", "), e.Err)
+}
+
+// Unwrap returns the underlying context error.
+func (e *CloseTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// CloseAllContext closes all databases managed by the factory, giving up waiting
+// when the context is done.
+// Every database is removed from the factory before closing starts, so the factory is
+// left empty and consistent whatever the outcome. Databases that miss the deadline keep
+// closing in the background and are reported in a *CloseTimeoutError.
+// This operation is thread-safe and uses a write lock.
+//
+// Parameters:
+//   - ctx: The context bounding how long to wait for databases to close
+//
+// Returns:
+//   - error: Close errors joined together, or a *CloseTimeoutError listing laggards
+func (f *BoltFactory) CloseAllContext(ctx context.Context) error {
+	f.lck.Lock()
+	databases := f.databases
+	f.databases = make(map[string]*BoltDatabase)
+	f.lck.Unlock()
+
+	type closeResult struct {
+		name string
+		err  error
+	}
+	results := make(chan closeResult, len(databases))
+	pending := make(map[string]struct{}, len(databases))
+	for name, db := range databases {
+		if db == nil {
+			continue
+		}
+		pending[name] = struct{}{}
+		go func() {
+			results <- closeResult{name: name, err: db.Close()}
+		}()
+	}
+
+	var errs []error
+	for len(pending) > 0 {
+		select {
+		case res := <-results:
+			delete(pending, res.name)
+			if res.err != nil {
+				errs = append(errs, fmt.Errorf("close database %s: %w", res.name, res.err))
+			}
+		case <-ctx.Done():
+			names := make([]string, 0, len(pending))
+			for name := range pending {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return errors.Join(append(errs, &CloseTimeoutError{Pending: names, Err: ctx.Err()})...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CloseOnSignal installs a signal handler that closes every database in the factory
+// when one of the given signals arrives, waiting at most timeout.
+// If no signals are given, SIGINT and SIGTERM are used. The handler fires once.
+//
+// Parameters:
+//   - timeout: The maximum time to wait for databases to close
+//   - onDone: Called with the result of CloseAllContext, may be nil
+//   - signals: The signals to handle
+//
+// Returns:
+//   - func(): A function that uninstalls the handler if it has not fired yet
+func (f *BoltFactory) CloseOnSignal(timeout time.Duration, onDone func(error), signals ...os.Signal) func() {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	stopCh := make(chan struct{})
+	signal.Notify(sigCh, signals...)
+
+	go func() {
+		defer signal.Stop(sigCh)
+		select {
+		case <-sigCh:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			err := f.CloseAllContext(ctx)
+			if onDone != nil {
+				onDone(err)
+			}
+		case <-stopCh:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stopCh) })
+	}
+}
@@ -0,0 +1,83 @@
+package boltdb
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCloseAllContextReportsLaggards(t *testing.T) {
+	f := newTestFactory(t, "fast", "slow")
+	slow := f.databases["slow"]
+
+	// An open write transaction blocks Close until it ends.
+	tx, err := slow.db.Load().Begin(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = f.CloseAllContext(ctx)
+
+	var timeout *CloseTimeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("CloseAllContext = %v, want a *CloseTimeoutError", err)
+	}
+	if len(timeout.Pending) != 1 || timeout.Pending[0] != "slow" {
+		t.Fatalf("Pending = %v, want [slow]", timeout.Pending)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CloseAllContext = %v, want it to wrap context.DeadlineExceeded", err)
+	}
+	if names, _ := f.GetDatabases(); len(names) != 0 {
+		t.Fatalf("factory still manages %v", names)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloseAllContextClosesEverything(t *testing.T) {
+	f := newTestFactory(t, "a", "b")
+	a := f.databases["a"]
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := f.CloseAllContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Set("b", "k", []byte("v")); err == nil {
+		t.Fatal("Set on a closed database succeeded")
+	}
+}
+
+func TestCloseOnSignal(t *testing.T) {
+	f := newTestFactory(t, "a")
+	a := f.databases["a"]
+
+	done := make(chan error, 1)
+	stop := f.CloseOnSignal(time.Second, func(err error) { done <- err }, syscall.SIGUSR1)
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("CloseAllContext from signal = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("signal handler did not run")
+	}
+	if names, _ := f.GetDatabases(); len(names) != 0 {
+		t.Fatalf("factory still manages %v after the signal", names)
+	}
+	if err := a.Set("b", "k", []byte("v")); err == nil {
+		t.Fatal("Set on a database closed by the signal handler succeeded")
+	}
+}
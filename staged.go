@@ -0,0 +1,128 @@
+package boltdb
+
+import (
+	"sync"
+
+	"github.com/boltdb/bolt"
+)
+
+// stagedWrite is a pending write held by a StagedWrapper.
+type stagedWrite struct {
+	value   []byte // The staged value, unused for deletes
+	deleted bool   // Whether the key is staged for deletion
+}
+
+// StagedWrapper is a bucket wrapper that buffers writes in memory until Flush.
+// Its own reads see the pending writes layered over the bucket, while other readers
+// of the database only see them once Flush commits them in a single transaction.
+// Discard drops all pending writes.
+type StagedWrapper struct {
+	lck        sync.Mutex
+	db         *BoltDatabase          // The underlying database instance
+	bucketName string                 // The bucket name this wrapper operates on
+	pending    map[string]stagedWrite // Pending writes by key
+}
+
+// NewStagedWrapper creates a new buffering wrapper for a specific bucket.
+//
+// Parameters:
+//   - db: The BoltDatabase instance to wrap
+//   - bucketName: The name of the bucket this wrapper will operate on
+//
+// Returns:
+//   - *StagedWrapper: A new wrapper instance with no pending writes
+func NewStagedWrapper(db *BoltDatabase, bucketName string) *StagedWrapper {
+	return &StagedWrapper{
+		db:         db,
+		bucketName: bucketName,
+		pending:    make(map[string]stagedWrite),
+	}
+}
+
+// Get retrieves a value, preferring a pending write over the stored value.
+//
+// Parameters:
+//   - key: The key to retrieve
+//
+// Returns:
+//   - []byte: The value associated with the key, or nil if not found or staged for deletion
+//   - error: Any error that occurred during the operation
+func (s *StagedWrapper) Get(key string) ([]byte, error) {
+	s.lck.Lock()
+	write, ok := s.pending[key]
+	s.lck.Unlock()
+	if ok {
+		if write.deleted {
+			return nil, nil
+		}
+		return write.value, nil
+	}
+	return s.db.Get(s.bucketName, key)
+}
+
+// Set stages a value to be stored on Flush.
+//
+// Parameters:
+//   - key: The key to store
+//   - value: The value to store (as bytes)
+func (s *StagedWrapper) Set(key string, value []byte) {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+	s.pending[key] = stagedWrite{value: value}
+}
+
+// Delete stages a key to be removed on Flush.
+//
+// Parameters:
+//   - key: The key to delete
+func (s *StagedWrapper) Delete(key string) {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+	s.pending[key] = stagedWrite{deleted: true}
+}
+
+// Flush applies all pending writes in a single transaction and clears them.
+// If the transaction fails, the pending writes are kept so Flush can be retried.
+//
+// Returns:
+//   - error: Any error that occurred while committing
+func (s *StagedWrapper) Flush() error {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+	if len(s.pending) == 0 {
+		return nil
+	}
+	if err := s.db.checkBucketName(s.bucketName); err != nil {
+		return err
+	}
+
+	err := s.db.update(func(tx *bolt.Tx) error {
+		bucket, err := s.db.createBucketIfNotExists(tx, s.bucketName)
+		if err != nil {
+			return err
+		}
+		for key, write := range s.pending {
+			if write.deleted {
+				err = s.db.deleteKey(tx, bucket, s.bucketName, key)
+			} else {
+				err = s.db.putKey(tx, bucket, s.bucketName, key, write.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	clear(s.pending)
+	return nil
+}
+
+// Discard drops all pending writes without applying them.
+func (s *StagedWrapper) Discard() {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+	clear(s.pending)
+}
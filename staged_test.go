@@ -0,0 +1,78 @@
+package boltdb
+
+import "testing"
+
+func TestStagedWrapperFlush(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "b", "existing", []byte("old"))
+	mustSet(t, db, "b", "doomed", []byte("v"))
+
+	s := NewStagedWrapper(db, "b")
+	s.Set("existing", []byte("new"))
+	s.Set("added", []byte("v"))
+	s.Delete("doomed")
+
+	for key, want := range map[string]string{"existing": "new", "added": "v", "doomed": ""} {
+		got, err := s.Get(key)
+		if err != nil || string(got) != want {
+			t.Fatalf("staged Get %s = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	for key, want := range map[string]string{"existing": "old", "added": "", "doomed": "v"} {
+		got, err := db.Get("b", key)
+		if err != nil || string(got) != want {
+			t.Fatalf("database Get %s before Flush = %q, %v; want %q", key, got, err, want)
+		}
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"existing": "new", "added": "v", "doomed": ""} {
+		got, err := db.Get("b", key)
+		if err != nil || string(got) != want {
+			t.Fatalf("database Get %s after Flush = %q, %v; want %q", key, got, err, want)
+		}
+	}
+}
+
+func TestStagedWrapperDiscard(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "b", "k", []byte("old"))
+
+	s := NewStagedWrapper(db, "b")
+	s.Set("k", []byte("new"))
+	s.Delete("k")
+	s.Set("other", []byte("v"))
+	s.Discard()
+
+	if got, err := s.Get("k"); err != nil || string(got) != "old" {
+		t.Fatalf("Get after Discard = %q, %v; want old", got, err)
+	}
+	if got, err := s.Get("other"); err != nil || got != nil {
+		t.Fatalf("Get discarded key = %q, %v; want nil", got, err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.Get("b", "other"); err != nil || got != nil {
+		t.Fatalf("Flush after Discard wrote %q, %v", got, err)
+	}
+}
+
+func TestStagedWrapperFlushIsAtomic(t *testing.T) {
+	db := newTestDB(t, nil)
+	s := NewStagedWrapper(db, "b")
+	s.Set("a", []byte("v"))
+	s.Set("", []byte("bolt rejects empty keys"))
+
+	if err := s.Flush(); err == nil {
+		t.Fatal("Flush with an invalid key succeeded")
+	}
+	if got, err := db.Get("b", "a"); err != nil || got != nil {
+		t.Fatalf("failed Flush wrote a = %q, %v", got, err)
+	}
+	if got, err := s.Get("a"); err != nil || string(got) != "v" {
+		t.Fatalf("pending write lost after failed Flush: %q, %v", got, err)
+	}
+}
@@ -0,0 +1,193 @@
+package boltdb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/boltdb/bolt"
+)
+
+// errDryRun is returned from a dry-run transaction to force bolt to roll it back.
+var errDryRun = errors.New("dry run")
+
+// PreparedTxn is a set of batches spanning several factory databases that have been
+// validated by Prepare and are ready to be committed or discarded.
+// Bolt transactions cannot span files, so Commit applies each database's batch in its
+// own transaction, one database after another. If a later database fails, the databases
+// that were already committed are reverted on a best-effort basis by restoring the values
+// their keys held before the commit. Buckets created by the commit are left in place.
+type PreparedTxn struct {
+	lck  sync.Mutex
+	dbs  map[string]*BoltDatabase
+	ops  map[string]map[string][]*WriteOperation // database -> bucket -> operations
+	done bool
+}
+
+// undoEntry records the value a key held before a prepared transaction overwrote it.
+type undoEntry struct {
+	bucket  []byte
+	key     []byte
+	value   []byte
+	existed bool
+}
+
+// Prepare validates a cross-database plan without writing anything.
+// Every batch is checked against its named database by applying it inside a transaction
+// that is always rolled back, so errors such as invalid operations surface before any
+// database is modified.
+// This operation is thread-safe and uses a read lock.
+//
+// Parameters:
+//   - plan: A map of database names to the batches to apply to them
+//
+// Returns:
+//   - *PreparedTxn: A prepared transaction ready to be committed or rolled back
+//   - error: An error if a database is unknown or a batch fails validation
+func (f *BoltFactory) Prepare(plan map[string]*BoltBatch) (*PreparedTxn, error) {
+	f.lck.RLock()
+	defer f.lck.RUnlock()
+
+	txn := &PreparedTxn{
+		dbs: make(map[string]*BoltDatabase, len(plan)),
+		ops: make(map[string]map[string][]*WriteOperation, len(plan)),
+	}
+	for name, batch := range plan {
+		db, ok := f.databases[name]
+		if !ok || db == nil {
+			return nil, fmt.Errorf("database %s not found", name)
+		}
+
+		ops := batch.snapshot()
+		err := db.update(func(tx *bolt.Tx) error {
+			if _, err := applyWithUndo(db, tx, ops); err != nil {
+				return err
+			}
+			return errDryRun
+		})
+		if !errors.Is(err, errDryRun) {
+			return nil, fmt.Errorf("prepare database %s: %w", name, err)
+		}
+
+		txn.dbs[name] = db
+		txn.ops[name] = ops
+	}
+	return txn, nil
+}
+
+// Commit applies the prepared batches, one database at a time in name order.
+// If a database fails to commit, the previously committed databases are reverted
+// on a best-effort basis and the original error is returned.
+//
+// Returns:
+//   - error: The commit error, joined with any errors from the reversal
+func (p *PreparedTxn) Commit() error {
+	p.lck.Lock()
+	defer p.lck.Unlock()
+	if p.done {
+		return errors.New("transaction already finished")
+	}
+	p.done = true
+
+	names := make([]string, 0, len(p.ops))
+	for name := range p.ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	undos := make(map[string][]undoEntry, len(names))
+	for _, name := range names {
+		var undo []undoEntry
+		err := p.dbs[name].update(func(tx *bolt.Tx) error {
+			var err error
+			undo, err = applyWithUndo(p.dbs[name], tx, p.ops[name])
+			return err
+		})
+		if err != nil {
+			err = fmt.Errorf("commit database %s: %w", name, err)
+			return errors.Join(err, p.revert(undos))
+		}
+		undos[name] = undo
+	}
+	return nil
+}
+
+// Rollback discards the prepared transaction without writing anything.
+// Calling Rollback after Commit has no effect.
+func (p *PreparedTxn) Rollback() {
+	p.lck.Lock()
+	defer p.lck.Unlock()
+	p.done = true
+	p.ops = nil
+}
+
+// revert restores the pre-commit values recorded for each committed database.
+func (p *PreparedTxn) revert(undos map[string][]undoEntry) error {
+	var errs []error
+	for name, undo := range undos {
+		db := p.dbs[name]
+		err := db.update(func(tx *bolt.Tx) error {
+			for i := len(undo) - 1; i >= 0; i-- {
+				entry := undo[i]
+				bucket := tx.Bucket(entry.bucket)
+				if bucket == nil {
+					continue
+				}
+				var err error
+				if entry.existed {
+					err = db.putKey(tx, bucket, string(entry.bucket), string(entry.key), entry.value)
+				} else {
+					err = db.deleteKey(tx, bucket, string(entry.bucket), string(entry.key))
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("revert database %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyWithUndo applies operations to db within tx and returns the previous state of
+// every key it touched, in application order.
+func applyWithUndo(db *BoltDatabase, tx *bolt.Tx, ops map[string][]*WriteOperation) ([]undoEntry, error) {
+	var undo []undoEntry
+	for bucketName, bucketOps := range ops {
+		bucket, err := db.createBucketIfNotExists(tx, bucketName)
+		if err != nil {
+			return nil, err
+		}
+		for _, op := range bucketOps {
+			if err := validateWriteOperation(db, op); err != nil {
+				return nil, err
+			}
+
+			prev, err := db.readValue(tx, bucketName, string(op.Key), bucket.Get(op.Key))
+			if err != nil {
+				return nil, err
+			}
+			undo = append(undo, undoEntry{
+				bucket:  []byte(bucketName),
+				key:     op.Key,
+				value:   append([]byte{}, prev...),
+				existed: hasKey(bucket, op.Key),
+			})
+
+			switch op.Op {
+			case OpSet:
+				err = db.putKey(tx, bucket, bucketName, string(op.Key), op.Value)
+			case OpDelete:
+				err = db.deleteKey(tx, bucket, bucketName, string(op.Key))
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return undo, nil
+}
@@ -0,0 +1,136 @@
+package boltdb
+
+import (
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+// newTestFactory returns a factory managing a fresh test database for each name.
+func newTestFactory(t *testing.T, names ...string) *BoltFactory {
+	t.Helper()
+	f := &BoltFactory{databases: make(map[string]*BoltDatabase)}
+	for _, name := range names {
+		f.databases[name] = newTestDB(t, nil)
+	}
+	return f
+}
+
+// batchOf returns a batch for db holding ops, failing the test if one is rejected.
+func batchOf(t *testing.T, db *BoltDatabase, ops ...*WriteOperation) *BoltBatch {
+	t.Helper()
+	batch := db.NewBatch()
+	for _, op := range ops {
+		if err := batch.Add(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return batch
+}
+
+func TestPreparedTxnCommit(t *testing.T) {
+	f := newTestFactory(t, "a", "b")
+	a, b := f.databases["a"], f.databases["b"]
+
+	txn, err := f.Prepare(map[string]*BoltBatch{
+		"a": batchOf(t, a, NewSetOp("data", "k", []byte("va"))),
+		"b": batchOf(t, b, NewSetOp("data", "k", []byte("vb"))),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.Get("data", "k"); err == nil && got != nil {
+		t.Fatalf("Prepare wrote %q", got)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"a": "va", "b": "vb"} {
+		if got, err := f.databases[name].Get("data", "k"); err != nil || string(got) != want {
+			t.Fatalf("database %s: Get = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if err := txn.Commit(); err == nil {
+		t.Fatal("second Commit succeeded")
+	}
+}
+
+func TestPreparedTxnRevertsOnFailure(t *testing.T) {
+	f := newTestFactory(t, "a", "b")
+	a, b := f.databases["a"], f.databases["b"]
+	mustSet(t, a, "data", "k", []byte("old"))
+
+	txn, err := f.Prepare(map[string]*BoltBatch{
+		"a": batchOf(t, a, NewSetOp("data", "k", []byte("new")), NewSetOp("data", "added", []byte("v"))),
+		"b": batchOf(t, b, NewSetOp("data", "k", []byte("v"))),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Turn b's target key into a nested bucket after Prepare, so b fails to commit
+	// after a, which commits first in name order, has already been written.
+	err = b.db.Load().Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("data"))
+		if err != nil {
+			return err
+		}
+		_, err = bucket.CreateBucket([]byte("k"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := txn.Commit(); err == nil {
+		t.Fatal("Commit succeeded although database b failed")
+	}
+	if got, err := a.Get("data", "k"); err != nil || string(got) != "old" {
+		t.Fatalf("a: Get k = %q, %v; want reverted to old", got, err)
+	}
+	if got, _ := a.Get("data", "added"); got != nil {
+		t.Fatalf("a: Get added = %q; want reverted to missing", got)
+	}
+}
+
+func TestPrepareRejectsInvalidBatchWithoutWriting(t *testing.T) {
+	f := newTestFactory(t, "a", "b")
+	a, b := f.databases["a"], f.databases["b"]
+
+	// An empty key passes Add but fails when the dry run writes it.
+	bad := NewSetOp("data", "", []byte("v"))
+	_, err := f.Prepare(map[string]*BoltBatch{
+		"a": batchOf(t, a, NewSetOp("data", "k", []byte("v"))),
+		"b": batchOf(t, b, bad),
+	})
+	if err == nil {
+		t.Fatal("Prepare accepted an operation that cannot be written")
+	}
+	for name, db := range f.databases {
+		if buckets := bucketNames(t, db); len(buckets) != 0 {
+			t.Fatalf("database %s has buckets %v after a failed Prepare", name, buckets)
+		}
+	}
+
+	if _, err := f.Prepare(map[string]*BoltBatch{"missing": a.NewBatch()}); err == nil {
+		t.Fatal("Prepare accepted an unknown database")
+	}
+}
+
+func TestPreparedTxnRollback(t *testing.T) {
+	f := newTestFactory(t, "a")
+	a := f.databases["a"]
+
+	txn, err := f.Prepare(map[string]*BoltBatch{"a": batchOf(t, a, NewSetOp("data", "k", []byte("v")))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn.Rollback()
+	if err := txn.Commit(); err == nil {
+		t.Fatal("Commit after Rollback succeeded")
+	}
+	if buckets := bucketNames(t, a); len(buckets) != 0 {
+		t.Fatalf("rolled back transaction wrote buckets %v", buckets)
+	}
+}
@@ -0,0 +1,79 @@
+package boltdb
+
+import (
+	"errors"
+)
+
+// VERSION_MARKER is the first byte of every value written by SetVersionedValue.
+// It is followed by the schema version byte and then the value itself.
+const VERSION_MARKER = 0xFE
+
+// ErrUnversioned is returned by GetVersionedValue when the stored value was not
+// written by SetVersionedValue.
+var ErrUnversioned = errors.New("value is not versioned")
+
+// SetVersionedValue stores a value tagged with a schema version, so readers can
+// migrate old formats on read.
+// If the bucket doesn't exist, it will be created automatically.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to store the data in
+//   - key: The key to store
+//   - version: The schema version of the value
+//   - value: The value to store (as bytes)
+//
+// Returns:
+//   - error: An error if the operation fails
+func (b *BoltDatabase) SetVersionedValue(bucketName, key string, version byte, value []byte) error {
+	tagged := make([]byte, 0, len(value)+2)
+	tagged = append(tagged, VERSION_MARKER, version)
+	tagged = append(tagged, value...)
+	return b.Set(bucketName, key, tagged)
+}
+
+// GetVersionedValue retrieves a value written by SetVersionedValue along with its
+// schema version. Values written without a version fail with ErrUnversioned and can
+// be read with Get instead.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to retrieve from
+//   - key: The key to retrieve
+//
+// Returns:
+//   - byte: The schema version of the value
+//   - []byte: The value without its version tag, or nil if not found
+//   - error: ErrUnversioned for untagged values, or any error from the read
+func (b *BoltDatabase) GetVersionedValue(bucketName, key string) (byte, []byte, error) {
+	stored, err := b.Get(bucketName, key)
+	if err != nil || stored == nil {
+		return 0, nil, err
+	}
+	if !isVersioned(stored) {
+		return 0, nil, ErrUnversioned
+	}
+	return stored[1], stored[2:], nil
+}
+
+// IsVersionedValue reports whether the value stored under key carries a schema version.
+// Detection relies on VERSION_MARKER, so raw values that happen to start with the
+// marker byte are reported as versioned.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to check
+//   - key: The key to check
+//
+// Returns:
+//   - bool: True if the key exists and its value is versioned
+//   - error: Any error that occurred during the operation
+func (b *BoltDatabase) IsVersionedValue(bucketName, key string) (bool, error) {
+	stored, err := b.Get(bucketName, key)
+	if err != nil {
+		return false, err
+	}
+	return isVersioned(stored), nil
+}
+
+// isVersioned reports whether a stored value carries a version tag.
+func isVersioned(stored []byte) bool {
+	return len(stored) >= 2 && stored[0] == VERSION_MARKER
+}
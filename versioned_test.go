@@ -0,0 +1,50 @@
+package boltdb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVersionedValueRoundTrip(t *testing.T) {
+	db := newTestDB(t, nil)
+	if err := db.SetVersionedValue("data", "v1", 1, []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetVersionedValue("data", "v2", 2, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetVersionedValue("data", "empty", 7, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]struct {
+		version byte
+		value   string
+	}{"v1": {1, "old"}, "v2": {2, "new"}, "empty": {7, ""}} {
+		version, value, err := db.GetVersionedValue("data", key)
+		if err != nil || version != want.version || string(value) != want.value {
+			t.Fatalf("%s: got %d %q %v, want %d %q", key, version, value, err, want.version, want.value)
+		}
+	}
+}
+
+func TestUnversionedValuesAreDistinguishable(t *testing.T) {
+	db := newTestDB(t, nil)
+	mustSet(t, db, "data", "raw", []byte("plain"))
+	if err := db.SetVersionedValue("data", "tagged", 1, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := db.GetVersionedValue("data", "raw"); !errors.Is(err, ErrUnversioned) {
+		t.Fatalf("raw value: got %v, want ErrUnversioned", err)
+	}
+	if ok, err := db.IsVersionedValue("data", "raw"); err != nil || ok {
+		t.Fatalf("raw value reported versioned: %v, %v", ok, err)
+	}
+	if ok, err := db.IsVersionedValue("data", "tagged"); err != nil || !ok {
+		t.Fatalf("tagged value reported unversioned: %v, %v", ok, err)
+	}
+	if _, value, err := db.GetVersionedValue("data", "missing"); err != nil || value != nil {
+		t.Fatalf("missing key: %q, %v", value, err)
+	}
+}
@@ -0,0 +1,103 @@
+package boltdb
+
+import (
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+// WATCH_BUFFER_SIZE is the number of undelivered events a watch channel holds before
+// further events for that subscriber are dropped.
+const WATCH_BUFFER_SIZE = 64
+
+// ChangeEvent describes a committed change to a key.
+type ChangeEvent struct {
+	Bucket  string // The bucket of the changed key
+	Key     string // The changed key
+	Value   []byte // The new value, nil when Deleted is true
+	Deleted bool   // Whether the key was deleted
+}
+
+// watcher is a subscriber to the change feed.
+type watcher struct {
+	bucketName string
+	prefix     string
+	ch         chan ChangeEvent
+}
+
+// Watch subscribes to the changes committed on the specified bucket.
+// See WatchPrefix for delivery semantics.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to watch
+//
+// Returns:
+//   - <-chan ChangeEvent: The channel receiving the change events
+//   - func(): A function that unsubscribes and closes the channel
+func (b *BoltDatabase) Watch(bucketName string) (<-chan ChangeEvent, func()) {
+	return b.WatchPrefix(bucketName, "")
+}
+
+// WatchPrefix subscribes to the changes committed on keys of the specified bucket that
+// start with prefix, whether made by Set, Delete, batches or the other write methods.
+// Events are sent after the transaction commits, in commit order. Sending never blocks
+// writers: when a subscriber falls WATCH_BUFFER_SIZE events behind, newer events for it
+// are dropped. Deleting a key that doesn't exist sends no event. Writes made directly
+// through the bolt bucket given by Update are not reported.
+//
+// Parameters:
+//   - bucketName: The name of the bucket to watch
+//   - prefix: The key prefix to match, or empty for every key
+//
+// Returns:
+//   - <-chan ChangeEvent: The channel receiving the change events
+//   - func(): A function that unsubscribes and closes the channel; no events are
+//     delivered once it returns, and calling it again has no effect
+func (b *BoltDatabase) WatchPrefix(bucketName, prefix string) (<-chan ChangeEvent, func()) {
+	w := &watcher{bucketName: bucketName, prefix: prefix, ch: make(chan ChangeEvent, WATCH_BUFFER_SIZE)}
+
+	b.watchLck.Lock()
+	if b.watchers == nil {
+		b.watchers = make(map[*watcher]struct{})
+	}
+	b.watchers[w] = struct{}{}
+	b.watchLck.Unlock()
+
+	cancel := func() {
+		b.watchLck.Lock()
+		defer b.watchLck.Unlock()
+		if _, ok := b.watchers[w]; ok {
+			delete(b.watchers, w)
+			close(w.ch)
+		}
+	}
+	return w.ch, cancel
+}
+
+// notifyOnCommit arranges for watchers to be told about a change once tx commits.
+func (b *BoltDatabase) notifyOnCommit(tx *bolt.Tx, bucketName, key string, value []byte, deleted bool) {
+	b.watchLck.RLock()
+	watching := len(b.watchers) > 0
+	b.watchLck.RUnlock()
+	if !watching {
+		return
+	}
+
+	event := ChangeEvent{Bucket: bucketName, Key: key, Deleted: deleted}
+	if !deleted {
+		event.Value = append([]byte{}, value...)
+	}
+	tx.OnCommit(func() {
+		b.watchLck.RLock()
+		defer b.watchLck.RUnlock()
+		for w := range b.watchers {
+			if w.bucketName != bucketName || !strings.HasPrefix(key, w.prefix) {
+				continue
+			}
+			select {
+			case w.ch <- event:
+			default:
+			}
+		}
+	})
+}
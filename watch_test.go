@@ -0,0 +1,137 @@
+package boltdb
+
+import (
+	"testing"
+	"time"
+)
+
+// nextEvent waits briefly for the next event on ch.
+func nextEvent(t *testing.T, ch <-chan ChangeEvent) (ChangeEvent, bool) {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		return ev, ok
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a change event")
+		return ChangeEvent{}, false
+	}
+}
+
+func TestWatchReportsSetsAndDeletes(t *testing.T) {
+	db := newTestDB(t, nil)
+	events, cancel := db.Watch("users")
+	defer cancel()
+
+	mustSet(t, db, "users", "alice", []byte("1"))
+	mustSet(t, db, "other", "bob", []byte("2"))
+	if err := db.Delete("users", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	ev, _ := nextEvent(t, events)
+	if ev.Bucket != "users" || ev.Key != "alice" || string(ev.Value) != "1" || ev.Deleted {
+		t.Fatalf("first event = %+v", ev)
+	}
+	ev, _ = nextEvent(t, events)
+	if ev.Key != "alice" || ev.Value != nil || !ev.Deleted {
+		t.Fatalf("second event = %+v", ev)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event %+v", ev)
+	default:
+	}
+}
+
+func TestWatchIgnoresDeletesOfMissingKeys(t *testing.T) {
+	db := newTestDB(t, nil)
+	events, cancel := db.Watch("users")
+	defer cancel()
+
+	mustSet(t, db, "users", "alice", []byte("1"))
+	if ev, _ := nextEvent(t, events); ev.Key != "alice" {
+		t.Fatalf("event = %+v, want alice", ev)
+	}
+	if err := db.Delete("users", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	mustSet(t, db, "users", "empty", []byte{})
+	if err := db.Delete("users", "empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ev, _ := nextEvent(t, events); ev.Key != "empty" || ev.Deleted {
+		t.Fatalf("event = %+v, want the set of empty", ev)
+	}
+	if ev, _ := nextEvent(t, events); ev.Key != "empty" || !ev.Deleted {
+		t.Fatalf("event = %+v, want the delete of empty", ev)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event %+v", ev)
+	default:
+	}
+}
+
+func TestWatchPrefixFiltersKeysAndUnsubscribes(t *testing.T) {
+	db := newTestDB(t, nil)
+	events, cancel := db.WatchPrefix("users", "user:")
+
+	mustSet(t, db, "users", "admin:root", []byte("x"))
+	mustSet(t, db, "users", "user:alice", []byte("y"))
+
+	ev, _ := nextEvent(t, events)
+	if ev.Key != "user:alice" || string(ev.Value) != "y" {
+		t.Fatalf("event = %+v, want user:alice", ev)
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event %+v", ev)
+	default:
+	}
+
+	cancel()
+	cancel()
+	mustSet(t, db, "users", "user:bob", []byte("z"))
+	if ev, ok := <-events; ok {
+		t.Fatalf("received %+v after unsubscribing", ev)
+	}
+}
+
+func TestWatchReportsBatchAndModifyWrites(t *testing.T) {
+	db := newTestDB(t, nil)
+	events, cancel := db.Watch("b")
+	defer cancel()
+
+	batch := db.NewBatch()
+	if err := batch.AddSet("b", "k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if event, _ := nextEvent(t, events); event.Key != "k" || string(event.Value) != "v" || event.Deleted {
+		t.Fatalf("batch set event = %+v", event)
+	}
+
+	err := db.Modify("b", "k", func(current []byte) ([]byte, error) {
+		return append(current, '2'), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event, _ := nextEvent(t, events); event.Key != "k" || string(event.Value) != "v2" {
+		t.Fatalf("Modify event = %+v", event)
+	}
+
+	batch.Reset()
+	if err := batch.AddDelete("b", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if event, _ := nextEvent(t, events); event.Key != "k" || !event.Deleted {
+		t.Fatalf("batch delete event = %+v", event)
+	}
+}
@@ -7,6 +7,7 @@ package boltdb
 type BoltDBWrapper struct {
 	db         *BoltDatabase // The underlying database instance
 	bucketName string        // The bucket name this wrapper operates on
+	codec      Codec         // The codec used by GetValue and SetValue, nil for JSON
 }
 
 // NewBatch creates a new write batch for the database.
@@ -90,3 +91,109 @@ func (w *BoltDBWrapper) List() (map[string][]byte, error) {
 func (w *BoltDBWrapper) ForEach(fn func(key, value []byte) error) error {
 	return w.db.ForEach(w.bucketName, fn)
 }
+
+// GetValue retrieves a value from the configured bucket and decodes it into v
+// using the wrapper's codec.
+//
+// Parameters:
+//   - key: The key to retrieve
+//   - v: A pointer to the value to decode into
+//
+// Returns:
+//   - bool: True if the key was found
+//   - error: Any error that occurred while reading or decoding
+func (w *BoltDBWrapper) GetValue(key string, v any) (bool, error) {
+	data, err := w.Get(key)
+	if err != nil || data == nil {
+		return false, err
+	}
+	return true, w.getCodec().Unmarshal(data, v)
+}
+
+// SetValue encodes v with the wrapper's codec and stores it in the configured bucket.
+//
+// Parameters:
+//   - key: The key to store
+//   - v: The value to encode and store
+//
+// Returns:
+//   - error: Any error that occurred while encoding or writing
+func (w *BoltDBWrapper) SetValue(key string, v any) error {
+	data, err := w.getCodec().Marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.Set(key, data)
+}
+
+// getCodec returns the wrapper's codec, defaulting to JSON.
+func (w *BoltDBWrapper) getCodec() Codec {
+	if w.codec == nil {
+		return JSONCodec{}
+	}
+	return w.codec
+}
+
+// Exists reports whether a key is present in the configured bucket without copying its value.
+// This is a convenience method that automatically uses the wrapper's bucket name.
+//
+// Parameters:
+//   - key: The key to look for
+//
+// Returns:
+//   - bool: True if the key is present, false if it or the bucket is missing
+//   - error: Any error that occurred during the operation
+func (w *BoltDBWrapper) Exists(key string) (bool, error) {
+	return w.db.Exists(w.bucketName, key)
+}
+
+// ScanPrefix iterates over the entries of the configured bucket whose keys start with prefix.
+// This is a convenience method that automatically uses the wrapper's bucket name.
+//
+// Parameters:
+//   - prefix: The key prefix to match, or empty for every key
+//   - fn: A function that will be called for each matching key-value pair
+//
+// Returns:
+//   - error: Any error that occurred during the operation
+func (w *BoltDBWrapper) ScanPrefix(prefix string, fn func(key, value []byte) error) error {
+	return w.db.ScanPrefix(w.bucketName, prefix, fn)
+}
+
+// Count returns the number of keys in the configured bucket.
+// This is a convenience method that automatically uses the wrapper's bucket name.
+//
+// Returns:
+//   - int: The number of keys, or 0 if the bucket doesn't exist
+//   - error: Any error that occurred during the operation
+func (w *BoltDBWrapper) Count() (int, error) {
+	return w.db.Count(w.bucketName)
+}
+
+// BatchGet retrieves many keys from the configured bucket in a single read transaction.
+// This is a convenience method that automatically uses the wrapper's bucket name.
+//
+// Parameters:
+//   - keys: The keys to retrieve
+//
+// Returns:
+//   - map[string][]byte: The value of every key found; missing keys are absent
+//   - error: Any error that occurred during the operation
+func (w *BoltDBWrapper) BatchGet(keys []string) (map[string][]byte, error) {
+	return w.db.BatchGet(w.bucketName, keys)
+}
+
+// Update atomically replaces the value stored under key in the configured bucket with the
+// result of fn, reading and writing in a single read-write transaction. Returning nil
+// from fn deletes the key. This is a convenience method that automatically uses the
+// wrapper's bucket name; see BoltDatabase.Modify.
+//
+// Parameters:
+//   - key: The key to update
+//   - fn: A function computing the new value from a copy of the current one, or nil if absent
+//
+// Returns:
+//   - error: Any error returned by fn or the transaction
+func (w *BoltDBWrapper) Update(key string, fn func(current []byte) ([]byte, error)) error {
+	return w.db.Modify(w.bucketName, key, fn)
+}
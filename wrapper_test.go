@@ -0,0 +1,87 @@
+package boltdb
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestWrapperUpdateConcurrentJSONCounter(t *testing.T) {
+	db := newTestDB(t, nil)
+	w := NewBoltDBWrapper(db, "counters")
+	const workers, perWorker = 8, 10
+
+	type counter struct {
+		Hits int `json:"hits"`
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				err := w.Update("page", func(current []byte) ([]byte, error) {
+					var c counter
+					if current != nil {
+						if err := json.Unmarshal(current, &c); err != nil {
+							return nil, err
+						}
+					}
+					c.Hits++
+					return json.Marshal(c)
+				})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var c counter
+	found, err := w.GetValue("page", &c)
+	if err != nil || !found || c.Hits != workers*perWorker {
+		t.Fatalf("counter = %+v, %v, %v; want %d hits", c, found, err, workers*perWorker)
+	}
+
+	if err := w.Update("page", func([]byte) ([]byte, error) { return nil, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := w.Get("page"); v != nil {
+		t.Fatalf("returning nil did not delete the key, got %q", v)
+	}
+}
+
+func TestScratchBucketCleanupDeletesIt(t *testing.T) {
+	db := newTestDB(t, nil)
+	scratch, cleanup, err := db.ScratchBucket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, otherCleanup, err := db.ScratchBucket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer otherCleanup()
+	if scratch.bucketName == other.bucketName {
+		t.Fatalf("two scratch buckets share the name %s", scratch.bucketName)
+	}
+
+	if err := scratch.Set("k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := scratch.Get("k"); err != nil || string(got) != "v" {
+		t.Fatalf("Get k = %q, %v", got, err)
+	}
+
+	if err := cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := db.HasBucket(scratch.bucketName); err != nil || ok {
+		t.Fatalf("HasBucket after cleanup = %v, %v; want false", ok, err)
+	}
+	if err := cleanup(); err != nil {
+		t.Fatalf("second cleanup: %v", err)
+	}
+}